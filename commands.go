@@ -0,0 +1,303 @@
+package main
+
+import (
+	"encoding/json"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Match page base and link targets for checking links.
+var basehref = regexp.MustCompile(`<base href="([^"]*)"`)
+var linkhref = regexp.MustCompile(`(?:href|src)="([^"]*)"`)
+
+// Matches link targets within pages for checking fragments.
+var anchorattr = regexp.MustCompile(`\s(?:id|name)="([^"]*)"`)
+
+// Lists what `build` generates as opposed to files placed in the output
+// directory by hand, such as templates and stylesheets.
+var generated = []string{"author", "branch", "commit", "object", "stats", "index.html", exportName}
+
+// Lists directories `build` shares with files placed by hand, cleared going
+// by their manifest only.
+var shared = []string{"assets", "page"}
+
+func serve(args []string) {
+	fs := flagset("serve")
+	addr := fs.String("a", "localhost:8080", "Address to listen on")
+	fs.Parse(args)
+
+	dir := target(fs.Arg(0))
+
+	log.Printf("serving %s at http://%s", dir, *addr)
+
+	if err := http.ListenAndServe(*addr, http.FileServer(http.Dir(dir))); err != nil {
+		log.Fatalf("unable to serve: %v", err)
+	}
+}
+
+func exportTemplate(args []string) {
+	fs := flagset("export-template")
+	quiet := fs.Bool("q", false, "Be quiet")
+	fs.Parse(args)
+
+	if *quiet {
+		log.SetOutput(io.Discard)
+	}
+
+	dir := filepath.Join(target(fs.Arg(0)), "templates")
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Fatalf("unable to create templates directory: %v", err)
+	}
+
+	sources, err := templateSources()
+
+	if err != nil {
+		log.Fatalf("unable to read default templates: %v", err)
+	}
+
+	for name, src := range sources {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			log.Fatalf("unable to export default template: %v", err)
+		}
+	}
+
+	log.Printf("done exporting default templates to %s", dir)
+}
+
+func initialize(args []string) {
+	opt := defaults()
+
+	fs := flagset("init")
+	buildflags(fs, opt)
+	fs.Parse(args)
+
+	if opt.Quiet {
+		log.SetOutput(io.Discard)
+	}
+
+	dir := target(fs.Arg(0))
+
+	// Existing settings are carried over unless overridden.
+	configure(fs, opt, dir)
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Fatalf("unable to create output directory: %v", err)
+	}
+
+	if err := opt.save(dir); err != nil {
+		log.Fatalf("unable to save options: %v", err)
+	}
+
+	log.Printf("done writing %s", filepath.Join(dir, opt.config))
+}
+
+func clean(args []string) {
+	fs := flagset("clean")
+	quiet := fs.Bool("q", false, "Be quiet")
+	fs.Parse(args)
+
+	if *quiet {
+		log.SetOutput(io.Discard)
+	}
+
+	dir := target(fs.Arg(0))
+	names := generated
+
+	// Projects archived side by side each get a subdirectory.
+	if file, err := readconfig(filepath.Join(dir, defaults().config)); err == nil {
+		var projects []repository
+
+		opt := defaults()
+
+		json.Unmarshal(file["name"], &opt.Name)
+		json.Unmarshal(file["projects"], &projects)
+
+		names = append(names, projectSlugs(projects, opt)...)
+	}
+
+	for _, name := range names {
+		if err := os.RemoveAll(filepath.Join(dir, name)); err != nil {
+			log.Fatalf("unable to remove %s: %v", name, err)
+		}
+
+		log.Printf("removed %s", name)
+	}
+
+	for _, name := range shared {
+		if err := sweep(filepath.Join(dir, name)); err != nil {
+			log.Fatalf("unable to clear %s: %v", name, err)
+		}
+
+		log.Printf("cleared %s", name)
+	}
+}
+
+func verify(args []string) {
+	fs := flagset("verify")
+	quiet := fs.Bool("q", false, "Be quiet")
+	fs.Parse(args)
+
+	if *quiet {
+		log.SetOutput(io.Discard)
+	}
+
+	dir := target(fs.Arg(0))
+
+	var pages, broken int
+
+	anchors := make(map[string]map[string]bool)
+
+	err := filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(p) != ".html" {
+			return err
+		}
+
+		bs, err := os.ReadFile(p)
+
+		if err != nil {
+			return err
+		}
+
+		pages++
+
+		for _, link := range brokenlinks(dir, p, string(bs), anchors) {
+			broken++
+
+			rel, _ := filepath.Rel(dir, p)
+			log.Printf("broken link in %s: %s", rel, link)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		log.Fatalf("unable to verify: %v", err)
+	}
+
+	log.Printf("checked %d pages, found %d broken links", pages, broken)
+
+	if broken > 0 {
+		os.Exit(1)
+	}
+}
+
+// Helps find links in a page pointing to files missing from `root`, or to
+// fragments missing from the pages linked to, going by the page's base URL
+// if any. External links and in page anchors are skipped. Targets found in
+// linked pages are cached in `anchors` per file.
+func brokenlinks(root string, page string, body string, anchors map[string]map[string]bool) []string {
+	var results []string
+
+	base := filepath.Dir(page)
+
+	if m := basehref.FindStringSubmatch(body); m != nil {
+		if strings.HasPrefix(m[1], "/") {
+			base = filepath.Join(root, m[1])
+		} else {
+			base = filepath.Join(base, m[1])
+		}
+
+		// The base URL itself is not a link.
+		body = strings.Replace(body, m[0], "", 1)
+	}
+
+	for _, m := range linkhref.FindAllStringSubmatch(body, -1) {
+		u, err := url.Parse(html.UnescapeString(m[1]))
+
+		// Skip external links and in page anchors.
+		if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" {
+			continue
+		}
+
+		p := filepath.Join(base, filepath.FromSlash(path.Clean(u.Path)))
+
+		if strings.HasPrefix(u.Path, "/") {
+			p = filepath.Join(root, filepath.FromSlash(u.Path))
+		}
+
+		fi, err := os.Stat(p)
+
+		if err == nil && fi.IsDir() {
+			p = filepath.Join(p, "index.html")
+			_, err = os.Stat(p)
+		}
+
+		if err != nil {
+			results = append(results, m[1])
+
+			continue
+		}
+
+		if u.Fragment != "" && filepath.Ext(p) == ".html" && !targets(p, anchors)[u.Fragment] {
+			results = append(results, m[1])
+		}
+	}
+
+	return results
+}
+
+// Helps list the ids and names a page has for linking to, reading each page once.
+func targets(p string, anchors map[string]map[string]bool) map[string]bool {
+	if ids, ok := anchors[p]; ok {
+		return ids
+	}
+
+	ids := make(map[string]bool)
+
+	if bs, err := os.ReadFile(p); err == nil {
+		for _, m := range anchorattr.FindAllStringSubmatch(string(bs), -1) {
+			ids[html.UnescapeString(m[1])] = true
+		}
+	}
+
+	anchors[p] = ids
+
+	return ids
+}
+
+func checkTemplate(args []string) {
+	fs := flagset("check-template")
+	quiet := fs.Bool("q", false, "Be quiet")
+	theme := fs.String("y", "", "Theme directory holding templates and assets")
+	fs.Parse(args)
+
+	if *quiet {
+		log.SetOutput(io.Discard)
+	}
+
+	t, err := loadTemplates(*theme, fs.Arg(0), funcMap())
+
+	if err != nil {
+		log.Fatalf("unable to load templates: %v", err)
+	}
+
+	var failed int
+
+	samples := fixtures()
+
+	for _, kind := range kinds {
+		for _, p := range samples[kind] {
+			if err := t.render(io.Discard, kind, p); err != nil {
+				failed++
+
+				log.Printf("%s: %v", kind, err)
+			}
+		}
+	}
+
+	if failed > 0 {
+		log.Printf("found %d template errors", failed)
+		os.Exit(1)
+	}
+
+	log.Printf("done checking templates")
+}
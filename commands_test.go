@@ -0,0 +1,98 @@
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBrokenLinks(t *testing.T) {
+	root := t.TempDir()
+
+	for p, body := range map[string]string{
+		"index.html":            "",
+		"commit/abc/index.html": "",
+		"commit/abc/diff.html":  `<li id="a&amp;b.go"></li><a name="top"></a>`,
+		"object/def":            "",
+	} {
+		p = filepath.Join(root, p)
+
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(p, []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	page := filepath.Join(root, "commit", "abc", "index.html")
+	body := `<base href="../../">
+<a href="index.html">home</a>
+<a href="commit/abc/">commit</a>
+<a href="object/def">object</a>
+<a href="object/missing">missing</a>
+<a href="branch/">no index</a>
+<a href="#top">anchor</a>
+<a href="https://example.com/">external</a>
+<a href="mailto:jimbo@example.com">mail</a>
+<a href="commit/abc/diff.html#a&amp;b.go">file</a>
+<a href="commit/abc/diff.html#top">named</a>
+<a href="commit/abc/diff.html#gone.go">gone</a>`
+
+	got := brokenlinks(root, page, body, make(map[string]map[string]bool))
+	want := []string{"object/missing", "branch/", "commit/abc/diff.html#gone.go"}
+
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestClean(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, p := range []string{"commit/abc/index.html", "index.html", "assets/style.css", "assets/logo.svg", "page/about/index.html", "page/notes.txt"} {
+		p = filepath.Join(dir, p)
+
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(p, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := writeManifest(filepath.Join(dir, "assets"), []string{"style.css"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeManifest(filepath.Join(dir, "page"), []string{"about/index.html"}); err != nil {
+		t.Fatal(err)
+	}
+
+	defer log.SetOutput(os.Stderr)
+
+	clean([]string{"-q", dir})
+
+	for p, want := range map[string]bool{
+		"commit":             false,
+		"index.html":         false,
+		"assets/style.css":   false,
+		"assets/" + manifest: false,
+		"page/about":         false,
+		"assets/logo.svg":    true,
+		"page/notes.txt":     true,
+	} {
+		if _, err := os.Stat(filepath.Join(dir, p)); (err == nil) != want {
+			t.Errorf("%s: got %v, want kept %v", p, err, want)
+		}
+	}
+}
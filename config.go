@@ -0,0 +1,308 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Versions the config file layout, bumped along with a migration whenever
+// keys are renamed, removed, or change meaning.
+const configVersion = 1
+
+// Upgrades config files one version at a time, indexed by the version migrated from.
+var migrations = []func(map[string]json.RawMessage){
+	// Unversioned files may carry the since removed `export` flag.
+	func(m map[string]json.RawMessage) {
+		delete(m, "export")
+	},
+}
+
+// Prefixes environment variables read as settings, e.g. `GTX_NAME`.
+const envPrefix = "GTX_"
+
+// Where a setting's effective value came from, in increasing order of precedence.
+const (
+	fromDefault = "default"
+	fromFile    = "file"
+	fromEnv     = "env"
+	fromFlag    = "flag"
+)
+
+// Ties an options field to its command line flag and config file key.
+type setting struct {
+	// Names the short flag, if any. Letters once taken by removed flags,
+	// `-e` for export say, stay unused.
+	flag  string
+	key   string
+	usage string
+	// Points to the options field being set.
+	value interface{}
+}
+
+// Env returns the environment variable name the setting is read from,
+// `GTX_SPLIT_LINES` say.
+func (s setting) Env() string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(s.key, "-", "_"))
+}
+
+// Helps load a setting from its string form, as found in the environment.
+// List settings are comma separated.
+func (s setting) parse(v string) error {
+	switch p := s.value.(type) {
+	case *string:
+		*p = v
+	case *bool:
+		b, err := strconv.ParseBool(v)
+
+		if err != nil {
+			return fmt.Errorf("invalid boolean: %v", err)
+		}
+
+		*p = b
+	case *int:
+		n, err := strconv.Atoi(v)
+
+		if err != nil {
+			return fmt.Errorf("invalid number: %v", err)
+		}
+
+		*p = n
+	case *manyflag:
+		*p = nil
+
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				*p = append(*p, item)
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported type %T", p)
+	}
+
+	return nil
+}
+
+// Returns default options.
+func defaults() *options {
+	return &options{
+		CollapseLines:  2000,
+		HotspotWindows: "30,90,365",
+		Locale:         defaultLocale,
+		Name:           "Jimbo",
+		SplitLines:     10000,
+		config:         ".jimmy.json",
+	}
+}
+
+// Lists settings in the order they appear in the settings table.
+func (o *options) settings() []setting {
+	return []setting{
+		{"n", "name", "Project title", &o.Name},
+		{"s", "source", "Source repository", &o.Source},
+		{"b", "branches", "Target branches", &o.Branches},
+		{"t", "template", "Page template file or directory", &o.Template},
+		{"q", "quiet", "Be quiet", &o.Quiet},
+		{"f", "force", "Force rebuild", &o.Force},
+		{"l", "split-lines", "Diff lines above which to split per file", &o.SplitLines},
+		{"m", "collapse-lines", "File diff lines above which to collapse", &o.CollapseLines},
+		{"a", "mailmap", "Mailmap file", &o.Mailmap},
+		{"p", "privacy", "Email privacy mode: omit, obfuscate, or hash", &o.Privacy},
+		{"k", "keyring", "GnuPG home or SSH allowed signers file", &o.Keyring},
+		{"g", "notes", "Git notes refs", &o.Notes},
+		{"x", "link-rules", "Cross reference rules as pattern=URL", &o.LinkRules},
+		{"d", "languages", "Language mappings as extension=name", &o.Languages},
+		{"w", "hotspot-windows", "Hotspot windows in days, comma separated", &o.HotspotWindows},
+		{"", "description", "Project description", &o.Description},
+		{"u", "homepage", "Project homepage URL", &o.Homepage},
+		{"o", "topics", "Project topics", &o.Topics},
+		{"y", "theme", "Theme directory holding templates and assets", &o.Theme},
+		{"c", "fingerprint", "Add content hashes to asset file names", &o.Fingerprint},
+		{"i", "pages", "Directory of extra pages in Markdown or HTML", &o.Pages},
+		{"v", "locale", "Page language, e.g. de or pt-BR", &o.Locale},
+		{"z", "zone", "Display time zone, e.g. Europe/Berlin", &o.Zone},
+	}
+}
+
+// Sets up flags for `build` and `init` alike, current values serving as
+// defaults. Each setting is also available as a long flag named after its
+// config file key, `-split-lines` say.
+func buildflags(fs *flag.FlagSet, opt *options) {
+	for _, s := range opt.settings() {
+		switch p := s.value.(type) {
+		case *string:
+			fs.StringVar(p, s.key, *p, s.usage)
+		case *bool:
+			fs.BoolVar(p, s.key, *p, s.usage)
+		case *int:
+			fs.IntVar(p, s.key, *p, s.usage)
+		case *manyflag:
+			fs.Var(p, s.key, s.usage)
+		}
+
+		if s.flag != "" {
+			fs.Var(fs.Lookup(s.key).Value, s.flag, s.usage)
+		}
+	}
+}
+
+// Layers settings from the config file in `dir`, if any, and the environment
+// underneath flags already parsed, then prints out the resulting settings
+// along with where each came from.
+func configure(fs *flag.FlagSet, opt *options, dir string) {
+	// Collect flags provided.
+	flagset := make(map[string]bool)
+
+	fs.Visit(func(f *flag.Flag) {
+		flagset[f.Name] = true
+	})
+
+	file, err := readconfig(filepath.Join(dir, opt.config))
+
+	// Carrying on would mean overwriting the file with defaults.
+	if err != nil {
+		log.Fatalf("unable to read config file: %v", err)
+	}
+
+	if raw, ok := file["$schema"]; ok {
+		json.Unmarshal(raw, &opt.Schema)
+	}
+
+	// Projects are only ever listed in the config file.
+	if raw, ok := file["projects"]; ok {
+		if err := json.Unmarshal(raw, &opt.Projects); err != nil {
+			log.Printf("unable to read config file key projects: %v", err)
+		}
+	}
+
+	settings := opt.settings()
+	known := map[string]bool{"$schema": true, "projects": true, "version": true}
+
+	for _, s := range settings {
+		known[s.key] = true
+	}
+
+	for _, k := range sortedkeys(file) {
+		if !known[k] {
+			log.Printf("unknown config file key: %s", k)
+		}
+	}
+
+	tab := tabwriter.NewWriter(log.Writer(), 0, 0, 0, '.', 0)
+
+	for _, s := range settings {
+		from := fromDefault
+
+		if flagset[s.flag] || flagset[s.key] {
+			from = fromFlag
+		} else if v, ok := os.LookupEnv(s.Env()); ok {
+			if err := s.parse(v); err != nil {
+				log.Printf("unable to read %s: %v", s.Env(), err)
+			} else {
+				from = fromEnv
+			}
+		} else if raw, ok := file[s.key]; ok {
+			if err := json.Unmarshal(raw, s.value); err != nil {
+				log.Printf("unable to read config file key %s: %v", s.key, err)
+			} else {
+				from = fromFile
+			}
+		}
+
+		fmt.Fprintf(tab, "gtx: -%s \t%s\t: %v (%s)\n", s.key, s.usage, fs.Lookup(s.key).Value, from)
+	}
+
+	tab.Flush()
+}
+
+// Helps read a config file into raw values by key, migrated to the current
+// version in memory only, a missing file counting as empty. Saving is left to
+// `init` and `-save`.
+func readconfig(p string) (map[string]json.RawMessage, error) {
+	results := make(map[string]json.RawMessage)
+
+	bs, err := os.ReadFile(p)
+
+	if os.IsNotExist(err) {
+		return results, nil
+	}
+
+	if err != nil {
+		return results, err
+	}
+
+	if err := json.Unmarshal(bs, &results); err != nil {
+		return results, fmt.Errorf("unable to parse config file: %v", err)
+	}
+
+	from, err := migrate(results)
+
+	if err != nil {
+		return results, err
+	}
+
+	if from != configVersion {
+		log.Printf("config file at version %d, save to bring it up to version %d", from, configVersion)
+	}
+
+	return results, nil
+}
+
+// Helps bring config file contents up to date, returning the version found.
+// Files lacking a version predate versioning altogether.
+func migrate(m map[string]json.RawMessage) (int, error) {
+	var v int
+
+	if raw, ok := m["version"]; ok {
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return v, fmt.Errorf("invalid config file version: %v", err)
+		}
+	}
+
+	if v > configVersion {
+		return v, fmt.Errorf("config file version %d is newer than supported version %d", v, configVersion)
+	}
+
+	for i := v; i < configVersion; i++ {
+		migrations[i](m)
+	}
+
+	m["version"] = json.RawMessage(strconv.Itoa(configVersion))
+
+	return v, nil
+}
+
+// Helps print out effective settings in config file form.
+func (o *options) print(w io.Writer) error {
+	o.Version = configVersion
+
+	bs, err := json.MarshalIndent(o, "", "  ")
+
+	if err != nil {
+		return fmt.Errorf("failed to encode options: %v", err)
+	}
+
+	_, err = fmt.Fprintln(w, string(bs))
+
+	return err
+}
+
+func sortedkeys(m map[string]json.RawMessage) []string {
+	var results []string
+
+	for k := range m {
+		results = append(results, k)
+	}
+
+	sort.Strings(results)
+
+	return results
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestConfigure(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	dir := t.TempDir()
+	cnf := `{"version": 1, "name": "File", "split-lines": 5, "collapse-lines": 7, "branches": ["main"], "bogus": true}`
+
+	if err := os.WriteFile(filepath.Join(dir, ".jimmy.json"), []byte(cnf), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("GTX_SPLIT_LINES", "6")
+	t.Setenv("GTX_COLLAPSE_LINES", "8")
+	t.Setenv("GTX_NOTES", "commits, review")
+
+	opt := defaults()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	buildflags(fs, opt)
+
+	if err := fs.Parse([]string{"-collapse-lines", "9"}); err != nil {
+		t.Fatal(err)
+	}
+
+	configure(fs, opt, dir)
+
+	if opt.Name != "File" {
+		t.Errorf("name: got %q, want file value", opt.Name)
+	}
+
+	if opt.SplitLines != 6 {
+		t.Errorf("split-lines: got %d, want env value", opt.SplitLines)
+	}
+
+	if opt.CollapseLines != 9 {
+		t.Errorf("collapse-lines: got %d, want flag value", opt.CollapseLines)
+	}
+
+	if opt.HotspotWindows != "30,90,365" {
+		t.Errorf("hotspot-windows: got %q, want default", opt.HotspotWindows)
+	}
+
+	if len(opt.Branches) != 1 || opt.Branches[0] != "main" {
+		t.Errorf("branches: got %v, want file value", opt.Branches)
+	}
+
+	if len(opt.Notes) != 2 || opt.Notes[1] != "review" {
+		t.Errorf("notes: got %v, want env value", opt.Notes)
+	}
+
+	// Letters of removed flags are not reused.
+	if fs.Lookup("e") != nil {
+		t.Error("-e taken up again")
+	}
+
+	// Short and long forms set the same value.
+	if fs.Lookup("m").Value.String() != "9" {
+		t.Errorf("m: got %v, want long flag value", fs.Lookup("m").Value)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	m := map[string]json.RawMessage{
+		"export": json.RawMessage("true"),
+		"name":   json.RawMessage(`"Jimbo"`),
+	}
+
+	from, err := migrate(m)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if from != 0 {
+		t.Errorf("got version %d, want 0", from)
+	}
+
+	if _, ok := m["export"]; ok {
+		t.Error("export key left in place")
+	}
+
+	if string(m["version"]) != strconv.Itoa(configVersion) {
+		t.Errorf("got version %s, want %d", m["version"], configVersion)
+	}
+
+	if _, err := migrate(map[string]json.RawMessage{"version": json.RawMessage("99")}); err == nil {
+		t.Error("newer version accepted")
+	}
+}
+
+func TestSchema(t *testing.T) {
+	bs, err := os.ReadFile("config.schema.json")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var schema struct {
+		Properties map[string]struct {
+			Const int `json:"const"`
+		} `json:"properties"`
+	}
+
+	if err := json.Unmarshal(bs, &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	if v := schema.Properties["version"].Const; v != configVersion {
+		t.Errorf("schema version %d, want %d", v, configVersion)
+	}
+
+	for _, s := range defaults().settings() {
+		if _, ok := schema.Properties[s.key]; !ok {
+			t.Errorf("schema missing %s", s.key)
+		}
+	}
+}
+
+func TestReadConfig(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	p := filepath.Join(t.TempDir(), ".jimmy.json")
+	cnf := []byte(`{"export": true, "split-lines": 5}`)
+
+	if err := os.WriteFile(p, cnf, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := readconfig(p)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := m["export"]; ok || string(m["split-lines"]) != "5" || string(m["version"]) != strconv.Itoa(configVersion) {
+		t.Errorf("got %v, want migrated contents", m)
+	}
+
+	// Reading alone leaves the file as found.
+	bs, err := os.ReadFile(p)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(bs) != string(cnf) {
+		t.Errorf("config file rewritten on read: %s", bs)
+	}
+}
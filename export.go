@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Names the machine readable summary written next to each project's home page.
+const exportName = "index.json"
+
+// Sums up a project for tools to read: what the home and stats pages show,
+// minus the markup.
+type export struct {
+	Branches    []exportBranch `json:"branches"`
+	Description string         `json:"description,omitempty"`
+	Homepage    string         `json:"homepage,omitempty"`
+	License     string         `json:"license,omitempty"`
+	Name        string         `json:"name"`
+	Source      string         `json:"source"`
+	Stats       exportStats    `json:"stats"`
+	Tags        []exportTag    `json:"tags,omitempty"`
+	Topics      []string       `json:"topics,omitempty"`
+}
+
+type exportBranch struct {
+	Commits   []exportCommit   `json:"commits"`
+	Languages []exportLanguage `json:"languages"`
+	Name      string           `json:"name"`
+}
+
+type exportTag struct {
+	Hash      string           `json:"hash"`
+	Languages []exportLanguage `json:"languages"`
+	Name      string           `json:"name"`
+}
+
+type exportCommit struct {
+	Author  exportAuthor `json:"author"`
+	Date    time.Time    `json:"date"`
+	Hash    string       `json:"hash"`
+	Notes   []exportNote `json:"notes,omitempty"`
+	Parents []string     `json:"parents,omitempty"`
+	Subject string       `json:"subject"`
+}
+
+type exportNote struct {
+	Body string `json:"body"`
+	Ref  string `json:"ref"`
+}
+
+type exportLanguage struct {
+	Bytes int64  `json:"bytes"`
+	Files int    `json:"files"`
+	Lines int    `json:"lines"`
+	Name  string `json:"name"`
+}
+
+type exportStats struct {
+	Authors []exportAuthor `json:"authors"`
+	Commits int            `json:"commits"`
+	Files   []exportFile   `json:"files"`
+	Monthly []exportBucket `json:"monthly"`
+	// Counts commits by weekday, starting on Sunday, and hour of day.
+	Punch  [7][24]int     `json:"punch"`
+	Weekly []exportBucket `json:"weekly"`
+}
+
+// Carries addresses as redacted by the privacy mode, a hash in their place when
+// hashing.
+type exportAuthor struct {
+	Commits int    `json:"commits,omitempty"`
+	Email   string `json:"email,omitempty"`
+	Hash    string `json:"hash,omitempty"`
+	Name    string `json:"name"`
+}
+
+type exportFile struct {
+	Additions int    `json:"additions"`
+	Commits   int    `json:"commits"`
+	Deletions int    `json:"deletions"`
+	Path      string `json:"path"`
+}
+
+type exportBucket struct {
+	Additions int       `json:"additions"`
+	Commits   int       `json:"commits"`
+	Deletions int       `json:"deletions"`
+	Start     time.Time `json:"start"`
+}
+
+// Helps put together the export for a project from what its pages are made of.
+func newExport(name string, source string, branches []branch, tags []tag, meta metadata) export {
+	results := export{
+		Branches:    []exportBranch{},
+		Description: meta.Description,
+		Homepage:    meta.Homepage,
+		License:     meta.License,
+		Name:        name,
+		Source:      source,
+		Topics:      meta.Topics,
+	}
+
+	for _, b := range branches {
+		eb := exportBranch{
+			Commits:   []exportCommit{},
+			Languages: exportLanguages(b.Languages),
+			Name:      b.Name,
+		}
+
+		for _, c := range b.Commits {
+			ec := exportCommit{
+				Author:  exportAuthor{Email: c.Author.Email, Hash: c.Author.Hash, Name: c.Author.Name},
+				Date:    c.Date,
+				Hash:    c.Hash,
+				Parents: c.Parents,
+				Subject: c.Subject,
+			}
+
+			for _, n := range c.Notes {
+				ec.Notes = append(ec.Notes, exportNote{Body: n.Body, Ref: n.Ref})
+			}
+
+			eb.Commits = append(eb.Commits, ec)
+		}
+
+		results.Branches = append(results.Branches, eb)
+	}
+
+	for _, t := range tags {
+		results.Tags = append(results.Tags, exportTag{
+			Hash:      t.Hash,
+			Languages: exportLanguages(t.Languages),
+			Name:      t.Name,
+		})
+	}
+
+	s := statistics(branches)
+
+	results.Stats = exportStats{
+		Authors: []exportAuthor{},
+		Commits: s.Commits,
+		Files:   []exportFile{},
+		Monthly: exportBuckets(s.Monthly),
+		Punch:   s.Punch,
+		Weekly:  exportBuckets(s.Weekly),
+	}
+
+	for _, a := range s.Authors {
+		results.Stats.Authors = append(results.Stats.Authors, exportAuthor{Commits: len(a.Commits), Email: a.Author.Email, Hash: a.Author.Hash, Name: a.Name})
+	}
+
+	for _, f := range s.Files {
+		results.Stats.Files = append(results.Stats.Files, exportFile{Additions: f.Adds, Commits: f.Commits, Deletions: f.Dels, Path: f.Path})
+	}
+
+	return results
+}
+
+func exportLanguages(b breakdown) []exportLanguage {
+	results := []exportLanguage{}
+
+	for _, l := range b {
+		results = append(results, exportLanguage{Bytes: l.Bytes, Files: l.Files, Lines: l.Lines, Name: l.Name})
+	}
+
+	return results
+}
+
+func exportBuckets(buckets []bucket) []exportBucket {
+	results := []exportBucket{}
+
+	for _, b := range buckets {
+		results = append(results, exportBucket{Additions: b.Adds, Commits: b.Commits, Deletions: b.Dels, Start: b.Start})
+	}
+
+	return results
+}
+
+// Helps write the export out as indented JSON.
+func writeExport(p string, e export) error {
+	bs, err := json.MarshalIndent(e, "", "  ")
+
+	if err != nil {
+		return fmt.Errorf("unable to encode export: %v", err)
+	}
+
+	if err := os.WriteFile(p, append(bs, '\n'), 0644); err != nil {
+		return fmt.Errorf("unable to write export: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExport(t *testing.T) {
+	repo := gitinit(t)
+
+	gitcommit(t, repo, map[string][]byte{
+		"LICENSE": []byte("MIT License\n\nPermission is hereby granted, free of charge, to any person obtaining a copy\n"),
+		"main.go": []byte("package main\n\nfunc main() {}\n"),
+	}, "First")
+	gitcommit(t, repo, map[string][]byte{"main.go": []byte("package main\n")}, "Second")
+	gitrun(t, repo, "tag", "v1")
+	gitrun(t, repo, "notes", "--ref=review", "add", "-m", "Looks good", "HEAD")
+
+	opt := defaults()
+	opt.Description = "Static git archives"
+	opt.Name = "Jimbo"
+	opt.Notes = manyflag{"review"}
+
+	dir := archive(t, repo, opt)
+	bs, err := os.ReadFile(filepath.Join(dir, exportName))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got export
+
+	if err := json.Unmarshal(bs, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Name != "Jimbo" || got.Description != "Static git archives" || got.License != "MIT" {
+		t.Errorf("got %+v, want project metadata", got)
+	}
+
+	if len(got.Branches) != 1 || len(got.Branches[0].Commits) != 2 || got.Branches[0].Commits[0].Subject != "Second" {
+		t.Fatalf("got %+v, want one branch with both commits, newest first", got.Branches)
+	}
+
+	if a := got.Branches[0].Commits[0].Author; a.Name != "Jo Doe" || a.Email != "jo@example.org" || a.Hash != "" {
+		t.Errorf("got %+v, want the author as is without a privacy mode", a)
+	}
+
+	if notes := got.Branches[0].Commits[0].Notes; len(notes) != 1 || notes[0].Body != "Looks good" || notes[0].Ref != "review" {
+		t.Errorf("got %+v, want the review note on the latest commit", notes)
+	}
+
+	if notes := got.Branches[0].Commits[1].Notes; notes != nil {
+		t.Errorf("got %+v, want no notes on the first commit", notes)
+	}
+
+	var golang exportLanguage
+
+	for _, l := range got.Branches[0].Languages {
+		if l.Name == "Go" {
+			golang = l
+		}
+	}
+
+	if golang.Files != 1 || golang.Lines != 1 || golang.Bytes != 13 {
+		t.Errorf("got %+v, want Go figures at the branch tip", golang)
+	}
+
+	if len(got.Tags) != 1 || got.Tags[0].Name != "v1" || len(got.Tags[0].Languages) == 0 {
+		t.Errorf("got %+v, want the tag along with its languages", got.Tags)
+	}
+
+	if got.Stats.Commits != 2 || len(got.Stats.Authors) != 1 || got.Stats.Authors[0].Commits != 2 || len(got.Stats.Weekly) == 0 {
+		t.Errorf("got %+v, want activity stats", got.Stats)
+	}
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"math"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Stands in for the current time, pinned down in tests.
+var now = time.Now
+
+// Match Markdown block and inline syntax.
+var (
+	mdheading  = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*$`)
+	mdbullet   = regexp.MustCompile(`^\s*[-*+]\s+(.*)$`)
+	mdnumber   = regexp.MustCompile(`^\s*\d+[.)]\s+(.*)$`)
+	mdrule     = regexp.MustCompile(`^\s*(?:(?:-\s*){3,}|(?:\*\s*){3,}|(?:_\s*){3,})$`)
+	mdcode     = regexp.MustCompile("`([^`]+)`")
+	mdlink     = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	mdstrong   = regexp.MustCompile(`\*\*(.+?)\*\*|__(.+?)__`)
+	mdemphasis = regexp.MustCompile(`\*([^*]+)\*|\b_([^_]+)_\b`)
+)
+
+// Lists helpers for template authors on top of the diff parsers.
+var helpers = template.FuncMap{
+	"markdown":   markdown,
+	"truncate":   truncate,
+	"joinpath":   joinpath,
+	"escapepath": escapepath,
+	"commiturl":  commiturl,
+	"objecturl":  objecturl,
+	"branchurl":  branchurl,
+	"authorurl":  authorurl,
+}
+
+// Lists units for telling how long ago something was, largest first, along
+// with catalog keys for one and more of each.
+var agounits = []struct {
+	one   string
+	other string
+	size  time.Duration
+}{
+	{"%d year ago", "%d years ago", 365 * 24 * time.Hour},
+	{"%d month ago", "%d months ago", 30 * 24 * time.Hour},
+	{"%d week ago", "%d weeks ago", 7 * 24 * time.Hour},
+	{"%d day ago", "%d days ago", 24 * time.Hour},
+	{"%d hour ago", "%d hours ago", time.Hour},
+	{"%d minute ago", "%d minutes ago", time.Minute},
+}
+
+// Helps describe how long ago `t` was relative to the time of building,
+// "3 days ago" say.
+func (l *locale) ago(t time.Time) string {
+	d := now().Sub(t)
+
+	if d < 0 {
+		return l.translate("in the future")
+	}
+
+	for _, u := range agounits {
+		switch n := int(d / u.size); {
+		case n == 1:
+			return l.translate(u.one, n)
+		case n > 1:
+			return l.translate(u.other, n)
+		}
+	}
+
+	return l.translate("just now")
+}
+
+// Helps spell out sizes in bytes using binary prefixes, "1.5 KiB" say.
+func (l *locale) bytesize(n int64) string {
+	const unit = 1024
+
+	if n < unit {
+		return l.plural(int(n), "byte", "bytes")
+	}
+
+	exp := int(math.Log(float64(n)) / math.Log(unit))
+
+	if exp > 6 {
+		exp = 6
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(n)/math.Pow(unit, float64(exp)), "KMGTPE"[exp-1])
+}
+
+// Helps shorten text to at most `n` characters, marking the cut with an ellipsis.
+func truncate(n int, s string) string {
+	if n <= 0 || utf8.RuneCountInString(s) <= n {
+		return s
+	}
+
+	r := []rune(s)
+
+	return strings.TrimSpace(string(r[:n-1])) + "…"
+}
+
+// Helps join URL path segments, cleaning up the result.
+func joinpath(elem ...string) string {
+	return path.Join(elem...)
+}
+
+// Helps escape each segment of a slash separated path for use in URLs.
+func escapepath(p string) string {
+	parts := strings.Split(p, "/")
+
+	for i, s := range parts {
+		parts[i] = url.PathEscape(s)
+	}
+
+	return strings.Join(parts, "/")
+}
+
+// Helps link to commit pages, relative to the page base.
+func commiturl(hash string) string {
+	return fmt.Sprintf("commit/%s/", hash)
+}
+
+// Helps link to object pages by blob hash, relative to the page base.
+func objecturl(hash string) string {
+	if len(hash) < 3 {
+		return ""
+	}
+
+	return fmt.Sprintf("object/%s/%s.html", hash[:2], hash[2:])
+}
+
+// Helps link to branch pages, relative to the page base.
+func branchurl(name string) string {
+	return fmt.Sprintf("branch/%s/", escapepath(name))
+}
+
+// Helps link to author pages, relative to the page base. Takes an author,
+// a contributor, or a name, names that slugify alike being told apart only
+// by the former.
+func authorurl(who interface{}) string {
+	switch a := who.(type) {
+	case author:
+		return fmt.Sprintf("author/%s/", a.Slug())
+	case contributor:
+		return fmt.Sprintf("author/%s/", a.Slug)
+	}
+
+	return fmt.Sprintf("author/%s/", slugify(fmt.Sprint(who)))
+}
+
+// Helps count things, "1 commit" or "2 commits" say, words looked up in the
+// catalog.
+func (l *locale) plural(n int, singular string, plural string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, l.translate(singular))
+	}
+
+	return fmt.Sprintf("%d %s", n, l.translate(plural))
+}
+
+// Helps render a commonly used subset of Markdown: headings, paragraphs,
+// lists, quotes, rules, fenced code blocks, and inline code, links, and
+// emphasis. Raw HTML is escaped.
+func markdown(s string) template.HTML {
+	var sb strings.Builder
+	var para, quote []string
+	var list string
+	var fence bool
+
+	flush := func() {
+		if len(para) > 0 {
+			fmt.Fprintf(&sb, "<p>%s</p>\n", inline(strings.Join(para, "\n")))
+			para = nil
+		}
+
+		if len(quote) > 0 {
+			fmt.Fprintf(&sb, "<blockquote>%s</blockquote>\n", markdown(strings.Join(quote, "\n")))
+			quote = nil
+		}
+
+		if list != "" {
+			fmt.Fprintf(&sb, "</%s>\n", list)
+			list = ""
+		}
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n") {
+		if fence {
+			if strings.HasPrefix(strings.TrimSpace(line), "```") {
+				sb.WriteString("</code></pre>\n")
+				fence = false
+			} else {
+				sb.WriteString(template.HTMLEscapeString(line))
+				sb.WriteString("\n")
+			}
+
+			continue
+		}
+
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			flush()
+			sb.WriteString("<pre><code>")
+			fence = true
+
+			continue
+		}
+
+		if strings.HasPrefix(line, ">") {
+			if len(quote) == 0 {
+				flush()
+			}
+
+			quote = append(quote, strings.TrimPrefix(strings.TrimPrefix(line, ">"), " "))
+
+			continue
+		}
+
+		if len(quote) > 0 {
+			flush()
+		}
+
+		if strings.TrimSpace(line) == "" {
+			flush()
+
+			continue
+		}
+
+		if m := mdheading.FindStringSubmatch(line); m != nil {
+			flush()
+			fmt.Fprintf(&sb, "<h%d>%s</h%d>\n", len(m[1]), inline(m[2]), len(m[1]))
+
+			continue
+		}
+
+		if mdrule.MatchString(line) {
+			flush()
+			sb.WriteString("<hr>\n")
+
+			continue
+		}
+
+		kind, item := "", ""
+
+		if m := mdbullet.FindStringSubmatch(line); m != nil {
+			kind, item = "ul", m[1]
+		} else if m := mdnumber.FindStringSubmatch(line); m != nil {
+			kind, item = "ol", m[1]
+		}
+
+		if kind != "" {
+			if list != kind {
+				flush()
+				fmt.Fprintf(&sb, "<%s>\n", kind)
+				list = kind
+			}
+
+			fmt.Fprintf(&sb, "<li>%s</li>\n", inline(item))
+
+			continue
+		}
+
+		if list != "" {
+			flush()
+		}
+
+		para = append(para, line)
+	}
+
+	if fence {
+		sb.WriteString("</code></pre>\n")
+	}
+
+	flush()
+
+	return template.HTML(sb.String())
+}
+
+// Helps render inline Markdown, leaving code spans alone.
+func inline(s string) string {
+	var sb strings.Builder
+
+	last := 0
+
+	for _, m := range mdcode.FindAllStringSubmatchIndex(s, -1) {
+		sb.WriteString(emphasize(s[last:m[0]]))
+		fmt.Fprintf(&sb, "<code>%s</code>", template.HTMLEscapeString(s[m[2]:m[3]]))
+		last = m[1]
+	}
+
+	sb.WriteString(emphasize(s[last:]))
+
+	return sb.String()
+}
+
+func emphasize(s string) string {
+	s = template.HTMLEscapeString(s)
+
+	s = mdlink.ReplaceAllStringFunc(s, func(m string) string {
+		sub := mdlink.FindStringSubmatch(m)
+
+		// Only web, mail, and relative links make it through.
+		if u, err := url.Parse(sub[2]); err != nil || (u.Scheme != "" && u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "mailto") {
+			return sub[1]
+		}
+
+		return fmt.Sprintf(`<a href="%s">%s</a>`, sub[2], sub[1])
+	})
+
+	s = mdstrong.ReplaceAllString(s, "<strong>$1$2</strong>")
+	s = mdemphasis.ReplaceAllString(s, "<em>$1$2</em>")
+
+	return s
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAgo(t *testing.T) {
+	at := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	now = func() time.Time { return at }
+	defer func() { now = time.Now }()
+
+	en, err := loadLocale("", "en", "")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	de, err := loadLocale("", "de", "")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		t    time.Time
+		want string
+	}{
+		{at.Add(30 * time.Second), "in the future"},
+		{at.Add(-30 * time.Second), "just now"},
+		{at.Add(-time.Minute), "1 minute ago"},
+		{at.Add(-5 * time.Hour), "5 hours ago"},
+		{at.AddDate(0, 0, -3), "3 days ago"},
+		{at.AddDate(0, 0, -14), "2 weeks ago"},
+		{at.AddDate(0, -2, 0), "1 month ago"},
+		{at.AddDate(-2, 0, 0), "2 years ago"},
+	}
+
+	for _, tt := range tests {
+		if got := en.ago(tt.t); got != tt.want {
+			t.Errorf("ago(%v) = %q, want %q", tt.t, got, tt.want)
+		}
+	}
+
+	if got := de.ago(at.AddDate(0, 0, -3)); got != "vor 3 Tagen" {
+		t.Errorf("got %q, want vor 3 Tagen", got)
+	}
+}
+
+func TestBytesize(t *testing.T) {
+	en, err := loadLocale("", "en", "")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{0, "0 bytes"},
+		{1, "1 byte"},
+		{1023, "1023 bytes"},
+		{1536, "1.5 KiB"},
+		{5 << 20, "5.0 MiB"},
+		{3 << 30, "3.0 GiB"},
+	}
+
+	for _, tt := range tests {
+		if got := en.bytesize(tt.n); got != tt.want {
+			t.Errorf("bytesize(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		n    int
+		s    string
+		want string
+	}{
+		{10, "short", "short"},
+		{0, "unlimited", "unlimited"},
+		{8, "a long subject line", "a long…"},
+		{3, "ünïcode", "ün…"},
+	}
+
+	for _, tt := range tests {
+		if got := truncate(tt.n, tt.s); got != tt.want {
+			t.Errorf("truncate(%d, %q) = %q, want %q", tt.n, tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestPaths(t *testing.T) {
+	tests := []struct {
+		got  string
+		want string
+	}{
+		{joinpath("commit", "abc", "../def", "a.go.html"), "commit/def/a.go.html"},
+		{escapepath("dir with space/a#b.go"), "dir%20with%20space/a%23b.go"},
+		{commiturl("abc123"), "commit/abc123/"},
+		{objecturl("abc123"), "object/ab/c123.html"},
+		{objecturl("a"), ""},
+		{branchurl("feature/x y"), "branch/feature/x%20y/"},
+		{authorurl("Jimbo Jones"), "author/jimbo-jones/"},
+		{authorurl(author{Name: "Jimbo Jones", slug: "jimbo-jones-abc123"}), "author/jimbo-jones-abc123/"},
+		{authorurl(contributor{Name: "Jimbo Jones", Slug: "jimbo-jones-2"}), "author/jimbo-jones-2/"},
+	}
+
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("got %q, want %q", tt.got, tt.want)
+		}
+	}
+}
+
+func TestPlural(t *testing.T) {
+	en, err := loadLocale("", "en", "")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for n, want := range map[int]string{0: "0 commits", 1: "1 commit", 2: "2 commits"} {
+		if got := en.plural(n, "commit", "commits"); got != want {
+			t.Errorf("plural(%d) = %q, want %q", n, got, want)
+		}
+	}
+
+	fr, err := loadLocale("", "fr", "")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := fr.plural(2, "byte", "bytes"); got != "2 octets" {
+		t.Errorf("got %q, want 2 octets", got)
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	src := strings.Join([]string{
+		"# Title",
+		"",
+		"Some *emphasis*, **strong** text and `<code>` with a [link](https://example.com).",
+		"Continued <b>here</b>.",
+		"",
+		"- one",
+		"- two",
+		"",
+		"1. first",
+		"2. second",
+		"",
+		"> quoted",
+		"",
+		"---",
+		"```",
+		"if a < b {",
+		"```",
+		"[bad](javascript:alert(1))",
+	}, "\n")
+
+	want := strings.Join([]string{
+		"<h1>Title</h1>",
+		`<p>Some <em>emphasis</em>, <strong>strong</strong> text and <code>&lt;code&gt;</code> with a <a href="https://example.com">link</a>.`,
+		"Continued &lt;b&gt;here&lt;/b&gt;.</p>",
+		"<ul>",
+		"<li>one</li>",
+		"<li>two</li>",
+		"</ul>",
+		"<ol>",
+		"<li>first</li>",
+		"<li>second</li>",
+		"</ol>",
+		"<blockquote><p>quoted</p>\n</blockquote>",
+		"<hr>",
+		"<pre><code>if a &lt; b {",
+		"</code></pre>",
+		"<p>bad)</p>",
+		"",
+	}, "\n")
+
+	if got := string(markdown(src)); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
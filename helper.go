@@ -1,22 +1,42 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"html"
 	"html/template"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
 )
 
 // Helps target file specific diff blocks.
-var diffanchor = regexp.MustCompile(`b\/(.*?)$`)
+var diffanchor = regexp.MustCompile(` b\/(.*?)$`)
 
-// Match diff body @@ del, ins line numbers.
-var aline = regexp.MustCompile(`\-(.*?),`)
-var bline = regexp.MustCompile(`\+(.*?),`)
+// Helps target file specific combined diff blocks.
+var ccanchor = regexp.MustCompile(`^diff --cc (.*?)$`)
+
+// Match diff body @@ del, ins line numbers, counts being optional.
+var aline = regexp.MustCompile(`^(@@ )\-(\d+)`)
+var bline = regexp.MustCompile(`^(@@+ .*? )\+(\d+)`)
+
+// Match diff body @@ hunk header starting line numbers.
+var hunkheader = regexp.MustCompile(`^@@ \-(\d+)(?:,\d+)? \+(\d+)(?:,\d+)? @@`)
 
 // Match diff body keywords.
 var xline = regexp.MustCompile(`^(deleted|index|new|rename|similarity)`)
 
+// Splits escaped diff lines into entities, words, whitespace runs, and single characters.
+var wordtoken = regexp.MustCompile(`&#?\w+;|\w+|\s+|.`)
+
+// Caps the number of token pairs compared per line so that pathological lines
+// fall back to plain whole line highlighting instead of stalling the build.
+const wordDiffLimit = 1 << 16
+
 // Helps decide if value contained in slice.
 // https://stackoverflow.com/questions/38654383/how-to-search-for-an-element-in-a-golang-slice
 func contains(s []string, n string) bool {
@@ -29,6 +49,17 @@ func contains(s []string, n string) bool {
 	return false
 }
 
+// Helps decide if a commit is contained in slice.
+func containsCommit(s []commit, h string) bool {
+	for _, c := range s {
+		if c.Hash == h {
+			return true
+		}
+	}
+
+	return false
+}
+
 // Helps clear duplicates in slice.
 // https://stackoverflow.com/questions/66643946/how-to-remove-duplicates-strings-or-int-from-slice-in-go
 func dedupe(input []string) []string {
@@ -45,28 +76,534 @@ func dedupe(input []string) []string {
 	return list
 }
 
+// Email privacy modes.
+const (
+	privacyOmit      = "omit"
+	privacyObfuscate = "obfuscate"
+	privacyHash      = "hash"
+)
+
+// Helps keep author email addresses out of the archive as requested.
+func anonymize(a author, mode string) author {
+	switch mode {
+	case privacyOmit:
+		a.Email = ""
+	case privacyObfuscate:
+		a.Email = strings.NewReplacer("@", " at ", ".", " dot ").Replace(a.Email)
+	case privacyHash:
+		sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(a.Email))))
+
+		a.Hash = hex.EncodeToString(sum[:])
+		a.Email = ""
+	}
+
+	return a
+}
+
+// Match email addresses in free text, leading space and angle brackets included if any.
+var emailaddress = regexp.MustCompile(`(\s?<?)([\w.+-]+@[\w-]+(?:\.[\w-]+)+)(>?)`)
+
+// Helps apply email privacy modes to free text such as commit messages and trailers.
+func scrub(s string, mode string) string {
+	if mode == "" {
+		return s
+	}
+
+	return emailaddress.ReplaceAllStringFunc(s, func(m string) string {
+		parts := emailaddress.FindStringSubmatch(m)
+		a := anonymize(author{Email: parts[2]}, mode)
+
+		if a.Email == "" {
+			return ""
+		}
+
+		return parts[1] + a.Email + parts[3]
+	})
+}
+
+// Helps draw a GitHub style 5x5 mirrored identicon out of a hex encoded hash.
+func identicon(hash string) template.HTML {
+	b, err := hex.DecodeString(hash)
+
+	if err != nil || len(b) < 16 {
+		return ""
+	}
+
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 5 5" width="40" height="40" fill="#%x">`, b[:3])
+
+	for i := 0; i < 15; i++ {
+		if b[3+i%13]>>(i/13)&1 == 0 {
+			continue
+		}
+
+		// Fill in the three left most columns, mirroring the first two.
+		x, y := i/5, i%5
+
+		fmt.Fprintf(&sb, `<rect x="%d" y="%d" width="1" height="1"/>`, x, y)
+
+		if x < 2 {
+			fmt.Fprintf(&sb, `<rect x="%d" y="%d" width="1" height="1"/>`, 4-x, y)
+		}
+	}
+
+	sb.WriteString("</svg>")
+
+	return template.HTML(sb.String())
+}
+
+// Helps parse comma separated day counts, all time included last.
+func days(s string) ([]int, error) {
+	var results []int
+
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v == "" {
+			continue
+		}
+
+		n, err := strconv.Atoi(v)
+
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid number of days: %q", v)
+		}
+
+		if !containsInt(results, n) {
+			results = append(results, n)
+		}
+	}
+
+	sort.Ints(results)
+
+	return append(results, 0), nil
+}
+
+// Helps decide if number contained in slice.
+func containsInt(s []int, n int) bool {
+	for _, v := range s {
+		if v == n {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Helps turn names into URL friendly directory names.
+func slugify(s string) string {
+	var sb strings.Builder
+
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			sb.WriteRune(r)
+		} else if sb.Len() > 0 && !strings.HasSuffix(sb.String(), "-") {
+			sb.WriteRune('-')
+		}
+	}
+
+	if slug := strings.TrimSuffix(sb.String(), "-"); slug != "" {
+		return slug
+	}
+
+	return "unknown"
+}
+
+// Helps give authors page directory names of their own. Names that slugify
+// the same as that of an author committing earlier, or to nothing at all, get
+// a short hash of the email address attached.
+func disambiguate(branches []branch) {
+	type identity struct {
+		author
+		first time.Time
+	}
+
+	var ids []identity
+
+	index := make(map[string]int)
+
+	for _, b := range branches {
+		for _, c := range b.Commits {
+			i, ok := index[c.Author.Name]
+
+			if !ok {
+				index[c.Author.Name] = len(ids)
+				ids = append(ids, identity{c.Author, c.Date})
+
+				continue
+			}
+
+			if c.Date.Before(ids[i].first) {
+				ids[i] = identity{c.Author, c.Date}
+			}
+		}
+	}
+
+	// Number off clashing names in a stable order, not whichever branch comes
+	// up first, so pages keep their addresses from one build to the next.
+	sort.SliceStable(ids, func(i, j int) bool {
+		if !ids[i].first.Equal(ids[j].first) {
+			return ids[i].first.Before(ids[j].first)
+		}
+
+		if ids[i].Email != ids[j].Email {
+			return ids[i].Email < ids[j].Email
+		}
+
+		return ids[i].Name < ids[j].Name
+	})
+
+	slugs := make(map[string]string)
+	taken := make(map[string]bool)
+
+	for _, a := range ids {
+		slug := slugify(a.Name)
+
+		if taken[slug] || slug == "unknown" {
+			id := a.Email
+
+			if id == "" {
+				id = a.Hash + a.Name
+			}
+
+			sum := sha256.Sum256([]byte(strings.ToLower(id)))
+			slug = fmt.Sprintf("%s-%x", slug, sum[:3])
+		}
+
+		// Fall back to counting in the unlikely case of hashes clashing too.
+		for n, base := 2, slug; taken[slug]; n++ {
+			slug = fmt.Sprintf("%s-%d", base, n)
+		}
+
+		slugs[a.Name] = slug
+		taken[slug] = true
+	}
+
+	for i := range branches {
+		for j := range branches[i].Commits {
+			a := &branches[i].Commits[j].Author
+			a.slug = slugs[a.Name]
+		}
+	}
+}
+
+// Helps group commits by author across branches, most active first.
+func contributors(branches []branch) []contributor {
+	var results []contributor
+
+	index := make(map[string]int)
+	seen := make(map[string]bool)
+
+	for _, b := range branches {
+		for _, c := range b.Commits {
+			if seen[c.Hash] {
+				continue
+			}
+
+			seen[c.Hash] = true
+
+			i, ok := index[c.Author.Name]
+
+			if !ok {
+				i = len(results)
+				index[c.Author.Name] = i
+				results = append(results, contributor{
+					Author: c.Author,
+					First:  c.Date,
+					Last:   c.Date,
+					Name:   c.Author.Name,
+					Slug:   c.Author.Slug(),
+				})
+			}
+
+			r := &results[i]
+			r.Commits = append(r.Commits, c)
+
+			if c.Author.Email != "" && !contains(r.Emails, c.Author.Email) {
+				r.Emails = append(r.Emails, c.Author.Email)
+			}
+
+			if c.Date.Before(r.First) {
+				r.First = c.Date
+			}
+
+			if c.Date.After(r.Last) {
+				r.Last = c.Date
+			}
+		}
+	}
+
+	// Credit co-authors known to have commits of their own.
+	for _, b := range branches {
+		for _, c := range b.Commits {
+			if !seen[c.Hash] {
+				continue
+			}
+
+			for _, a := range c.CoAuthors() {
+				i, ok := index[a.Name]
+
+				// Trailers may spell names differently, addresses being more telling.
+				for j := range results {
+					if ok || a.Email == "" {
+						break
+					}
+
+					if contains(results[j].Emails, a.Email) {
+						i, ok = j, true
+					}
+				}
+
+				if ok && !containsCommit(results[i].CoAuthored, c.Hash) {
+					results[i].CoAuthored = append(results[i].CoAuthored, c)
+				}
+			}
+		}
+	}
+
+	for _, r := range results {
+		sort.SliceStable(r.Commits, func(i, j int) bool {
+			return r.Commits[i].Date.After(r.Commits[j].Date)
+		})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if len(results[i].Commits) != len(results[j].Commits) {
+			return len(results[i].Commits) > len(results[j].Commits)
+		}
+
+		return results[i].Name < results[j].Name
+	})
+
+	return results
+}
+
+// Match bare URLs in free text.
+var bareurl = regexp.MustCompile(`https?://[^\s<>"]+`)
+
+// Match what might be commit hashes in free text, plain numbers aside.
+var barehash = regexp.MustCompile(`\b[0-9]*[a-f][0-9a-f]*\b`)
+
+// Helps parse `pattern=URL` cross reference rules, invalid ones are skipped.
+func linkrules(specs []string) ([]linkrule, error) {
+	var results []linkrule
+	var errs []string
+
+	for _, spec := range specs {
+		pattern, url, ok := strings.Cut(spec, "=")
+
+		if !ok {
+			errs = append(errs, fmt.Sprintf("missing URL: %q", spec))
+
+			continue
+		}
+
+		re, err := regexp.Compile(pattern)
+
+		if err != nil {
+			errs = append(errs, err.Error())
+
+			continue
+		}
+
+		results = append(results, linkrule{Pattern: re, URL: url})
+	}
+
+	if len(errs) > 0 {
+		return results, fmt.Errorf("invalid link rules: %s", strings.Join(errs, ", "))
+	}
+
+	return results, nil
+}
+
+// Helps link up issue references, bare URLs, and known commits in free text.
+// Hashes are expected sorted so that abbreviations can be looked up.
+func autolink(s string, rules []linkrule, hashes []string) template.HTML {
+	type link struct {
+		start, end int
+		href       string
+	}
+
+	var links []link
+
+	// Earlier rules win over later ones when overlapping.
+	for _, r := range rules {
+		for _, m := range r.Pattern.FindAllStringSubmatchIndex(s, -1) {
+			href := r.Pattern.ExpandString(nil, r.URL, s, m)
+			links = append(links, link{m[0], m[1], string(href)})
+		}
+	}
+
+	for _, m := range bareurl.FindAllStringIndex(s, -1) {
+		// Leave out trailing punctuation likely belonging to the sentence.
+		end := m[0] + len(strings.TrimRight(s[m[0]:m[1]], ".,;:!?)'"))
+		links = append(links, link{m[0], end, s[m[0]:end]})
+	}
+
+	for _, m := range barehash.FindAllStringIndex(s, -1) {
+		h := s[m[0]:m[1]]
+
+		if len(h) < 7 || len(h) > 40 {
+			continue
+		}
+
+		i := sort.SearchStrings(hashes, h)
+
+		if i < len(hashes) && strings.HasPrefix(hashes[i], h) {
+			links = append(links, link{m[0], m[1], fmt.Sprintf("commit/%s/", hashes[i])})
+		}
+	}
+
+	sort.SliceStable(links, func(i, j int) bool {
+		return links[i].start < links[j].start
+	})
+
+	var sb strings.Builder
+	var last int
+
+	for _, l := range links {
+		if l.start < last || l.start == l.end {
+			continue
+		}
+
+		sb.WriteString(template.HTMLEscapeString(s[last:l.start]))
+		fmt.Fprintf(&sb, `<a href="%s">%s</a>`, template.HTMLEscapeString(l.href), template.HTMLEscapeString(s[l.start:l.end]))
+
+		last = l.end
+	}
+
+	sb.WriteString(template.HTMLEscapeString(s[last:]))
+
+	return template.HTML(sb.String())
+}
+
+// Helps extract the target file path out of diff header lines.
+func diffpath(line string) string {
+	if m := ccanchor.FindStringSubmatch(line); m != nil {
+		return m[1]
+	}
+
+	if m := diffanchor.FindStringSubmatch(line); m != nil {
+		return m[1]
+	}
+
+	return ""
+}
+
+// Helps point to the raw patch for file diffs too big to show inline.
+func collapsenote(d diff, line string) string {
+	path := diffpath(line)
+
+	if path == "" {
+		return ""
+	}
+
+	n, ok := d.Collapsed[html.UnescapeString(path)]
+
+	if !ok {
+		return ""
+	}
+
+	return fmt.Sprintf(`<em>%d lines collapsed, see the <a href="commit/%s/%s">raw patch</a></em>`, n, d.Commit.Hash, d.Patch())
+}
+
 func diffbodyparser(d diff) template.HTML {
 	var results []string
 	feed := strings.Split(strings.TrimSuffix(template.HTMLEscapeString(d.Body), "\n"), "\n")
 
 	var a, b string
+	var hunk bool
+
+	// Combined diffs carry one marker column per parent.
+	cols := len(d.Commit.Parents)
+
+	// Removed and added lines waiting to be paired up for word highlighting.
+	var dels, inss []string
+
+	flush := func() {
+		for i := range dels {
+			if i < len(inss) {
+				dels[i], inss[i] = worddiff(dels[i], inss[i])
+			}
+
+			results = append(results, fmt.Sprintf("<del>-%s</del>", dels[i]))
+		}
+
+		for _, v := range inss {
+			results = append(results, fmt.Sprintf("<ins>+%s</ins>", v))
+		}
+
+		dels = nil
+		inss = nil
+	}
 
 	for _, line := range feed {
+		if d.Combined && hunk && !strings.HasPrefix(line, "diff") && !strings.HasPrefix(line, "@@") {
+			marks := line
+
+			if len(marks) > cols {
+				marks = marks[:cols]
+			}
+
+			if strings.Contains(marks, "-") {
+				line = fmt.Sprintf("<del>%s</del>", line)
+			} else if strings.Contains(marks, "+") {
+				line = fmt.Sprintf("<ins>%s</ins>", line)
+			}
+
+			results = append(results, line)
+
+			continue
+		}
+
+		if strings.HasPrefix(line, "-") && !strings.HasPrefix(line, "---") {
+			if len(inss) > 0 {
+				flush()
+			}
+
+			dels = append(dels, line[1:])
+
+			continue
+		}
+
+		if strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "+++") {
+			inss = append(inss, line[1:])
+
+			continue
+		}
+
+		flush()
+
 		if strings.HasPrefix(line, "diff") {
-			line = diffanchor.ReplaceAllString(line, `b/<a id="$1">$1</a>`)
+			note := collapsenote(d, line)
+
+			hunk = false
+
+			if d.Combined {
+				line = ccanchor.ReplaceAllString(line, `diff --cc <a id="$1">$1</a>`)
+			} else {
+				line = diffanchor.ReplaceAllString(line, ` b/<a id="$1">$1</a>`)
+			}
+
 			line = fmt.Sprintf("<strong>%s</strong>", line)
+
+			if note != "" {
+				line = fmt.Sprintf("%s\n%s", line, note)
+			}
 		}
 
 		line = xline.ReplaceAllString(line, "<em>$1</em>")
 
 		if strings.HasPrefix(line, "@@") {
-			if a != "" && !strings.HasPrefix(a, "---") {
-				repl := fmt.Sprintf(`<a href="commit/%s/%s.html#L$1">-$1</a>,`, d.Parent, a)
+			hunk = true
+
+			// Combined hunk headers list one range per parent, link the result only.
+			if a != "" && !strings.HasPrefix(a, "---") && !d.Combined {
+				repl := fmt.Sprintf(`$1<a href="commit/%s/%s.html#L$2">-$2</a>`, d.Parent, a)
 				line = aline.ReplaceAllString(line, repl)
 			}
 
 			if b != "" && !strings.HasPrefix(b, "+++") {
-				repl := fmt.Sprintf(`<a href="commit/%s/%s.html#L$1">+$1</a>,`, d.Commit.Hash, b)
+				repl := fmt.Sprintf(`$1<a href="commit/%s/%s.html#L$2">+$2</a>`, d.Commit.Hash, b)
 				line = bline.ReplaceAllString(line, repl)
 			}
 		}
@@ -74,38 +611,343 @@ func diffbodyparser(d diff) template.HTML {
 		if strings.HasPrefix(line, "---") {
 			a = strings.TrimPrefix(line, "--- a/")
 			line = fmt.Sprintf("<mark>%s</mark>", line)
-		} else if strings.HasPrefix(line, "-") {
-			line = fmt.Sprintf("<del>%s</del>", line)
 		}
 
 		if strings.HasPrefix(line, "+++") {
 			b = strings.TrimPrefix(line, "+++ b/")
 			line = fmt.Sprintf("<mark>%s</mark>", line)
-		} else if strings.HasPrefix(line, "+") {
-			line = fmt.Sprintf("<ins>%s</ins>", line)
 		}
 
 		results = append(results, line)
 	}
 
+	flush()
+
+	return template.HTML(strings.Join(results, "\n"))
+}
+
+// Helps break up a diff into per file patches.
+func patchsplitter(body string) []patch {
+	var results []patch
+	feed := strings.SplitAfter(body, "\n")
+
+	for _, line := range feed {
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "diff") || len(results) == 0 {
+			path := diffpath(strings.TrimSuffix(line, "\n"))
+
+			results = append(results, patch{Index: len(results) + 1, Path: path})
+		}
+
+		p := &results[len(results)-1]
+
+		p.Body += line
+		p.Lines++
+	}
+
+	return results
+}
+
+// Helps drop the hunks from a file patch, keeping only the header lines.
+func patchhead(body string) string {
+	if i := strings.Index(body, "\n@@"); i != -1 {
+		return body[:i+1]
+	}
+
+	return body
+}
+
+// Helps highlight the words that differ between a removed and an added line.
+// Both lines are expected to be HTML escaped already and are returned as is
+// if nothing sensible can be highlighted.
+func worddiff(a, b string) (string, string) {
+	x := wordtoken.FindAllString(a, -1)
+	y := wordtoken.FindAllString(b, -1)
+
+	// Skip over the common prefix and suffix to keep the table small.
+	var pre, suf int
+
+	for pre < len(x) && pre < len(y) && x[pre] == y[pre] {
+		pre++
+	}
+
+	for suf < len(x)-pre && suf < len(y)-pre && x[len(x)-1-suf] == y[len(y)-1-suf] {
+		suf++
+	}
+
+	if pre+suf == len(x) && pre+suf == len(y) {
+		return a, b
+	}
+
+	mx := x[pre : len(x)-suf]
+	my := y[pre : len(y)-suf]
+
+	if len(mx)*len(my) > wordDiffLimit {
+		return a, b
+	}
+
+	// Longest common subsequence lengths for each pair of suffixes.
+	lcs := make([][]int, len(mx)+1)
+
+	for i := range lcs {
+		lcs[i] = make([]int, len(my)+1)
+	}
+
+	for i := len(mx) - 1; i >= 0; i-- {
+		for j := len(my) - 1; j >= 0; j-- {
+			if mx[i] == my[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	// Nothing in common, wrapping everything adds no value.
+	if pre+suf+lcs[0][0] == 0 {
+		return a, b
+	}
+
+	kx := make([]bool, len(mx))
+	ky := make([]bool, len(my))
+
+	for i, j := 0, 0; i < len(mx) && j < len(my); {
+		switch {
+		case mx[i] == my[j]:
+			kx[i] = true
+			ky[j] = true
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+
+	return wordmark(x, pre, kx), wordmark(y, pre, ky)
+}
+
+// Helps wrap runs of changed tokens in <mark> tags.
+func wordmark(tokens []string, pre int, kept []bool) string {
+	var sb strings.Builder
+	var open bool
+
+	for i, t := range tokens {
+		k := i - pre
+		changed := k >= 0 && k < len(kept) && !kept[k]
+
+		if changed && !open {
+			sb.WriteString("<mark>")
+		}
+
+		if !changed && open {
+			sb.WriteString("</mark>")
+		}
+
+		open = changed
+		sb.WriteString(t)
+	}
+
+	if open {
+		sb.WriteString("</mark>")
+	}
+
+	return sb.String()
+}
+
+// Helps render a diff side by side, pairing up removed and added lines per hunk.
+func diffsplitparser(d diff) template.HTML {
+	var results []string
+	feed := strings.Split(strings.TrimSuffix(template.HTMLEscapeString(d.Body), "\n"), "\n")
+
+	var a, b string
+	var l, r int
+	var hunk bool
+
+	// Removed and added lines waiting to be paired up.
+	var dels, inss []string
+
+	// Links line numbers to the matching object page where possible.
+	cell := func(n int, hash, path, prefix string) string {
+		if n == 0 {
+			return "<td></td>"
+		}
+
+		if path == "" || strings.HasPrefix(path, prefix) {
+			return fmt.Sprintf("<td>%d</td>", n)
+		}
+
+		return fmt.Sprintf(`<td><a href="commit/%s/%s.html#L%d">%d</a></td>`, hash, path, n, n)
+	}
+
+	flush := func() {
+		for i := 0; i < len(dels) || i < len(inss); i++ {
+			var row []string
+
+			if i < len(dels) && i < len(inss) {
+				dels[i], inss[i] = worddiff(dels[i], inss[i])
+			}
+
+			if i < len(dels) {
+				l++
+				row = append(row, cell(l, d.Parent, a, "---"), fmt.Sprintf("<td><del>%s</del></td>", dels[i]))
+			} else {
+				row = append(row, "<td></td>", "<td></td>")
+			}
+
+			if i < len(inss) {
+				r++
+				row = append(row, cell(r, d.Commit.Hash, b, "+++"), fmt.Sprintf("<td><ins>%s</ins></td>", inss[i]))
+			} else {
+				row = append(row, "<td></td>", "<td></td>")
+			}
+
+			results = append(results, fmt.Sprintf("<tr>%s</tr>", strings.Join(row, "")))
+		}
+
+		dels = nil
+		inss = nil
+	}
+
+	// Spans all four columns for file and hunk headers.
+	span := func(line string) {
+		results = append(results, fmt.Sprintf(`<tr><th colspan="4">%s</th></tr>`, line))
+	}
+
+	for _, line := range feed {
+		if strings.HasPrefix(line, "diff") {
+			flush()
+
+			hunk = false
+			a, b = "", ""
+
+			note := collapsenote(d, line)
+
+			line = diffanchor.ReplaceAllString(line, ` b/<a id="$1">$1</a>`)
+			span(fmt.Sprintf("<strong>%s</strong>", line))
+
+			if note != "" {
+				span(note)
+			}
+
+			continue
+		}
+
+		if strings.HasPrefix(line, "@@") {
+			flush()
+
+			hunk = true
+
+			if m := hunkheader.FindStringSubmatch(line); m != nil {
+				// Counters are incremented ahead of each row.
+				l, _ = strconv.Atoi(m[1])
+				r, _ = strconv.Atoi(m[2])
+
+				if l > 0 {
+					l--
+				}
+
+				if r > 0 {
+					r--
+				}
+			}
+
+			if a != "" && !strings.HasPrefix(a, "---") {
+				repl := fmt.Sprintf(`$1<a href="commit/%s/%s.html#L$2">-$2</a>`, d.Parent, a)
+				line = aline.ReplaceAllString(line, repl)
+			}
+
+			if b != "" && !strings.HasPrefix(b, "+++") {
+				repl := fmt.Sprintf(`$1<a href="commit/%s/%s.html#L$2">+$2</a>`, d.Commit.Hash, b)
+				line = bline.ReplaceAllString(line, repl)
+			}
+
+			span(line)
+
+			continue
+		}
+
+		if !hunk {
+			if strings.HasPrefix(line, "---") {
+				a = strings.TrimPrefix(line, "--- a/")
+				line = fmt.Sprintf("<mark>%s</mark>", line)
+			} else if strings.HasPrefix(line, "+++") {
+				b = strings.TrimPrefix(line, "+++ b/")
+				line = fmt.Sprintf("<mark>%s</mark>", line)
+			} else {
+				line = xline.ReplaceAllString(line, "<em>$1</em>")
+			}
+
+			span(line)
+
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "-"):
+			dels = append(dels, line[1:])
+		case strings.HasPrefix(line, "+"):
+			inss = append(inss, line[1:])
+		case strings.HasPrefix(line, "\\"):
+			// Covers the "No newline at end of file" marker.
+			flush()
+			span(fmt.Sprintf("<em>%s</em>", line))
+		default:
+			flush()
+
+			l++
+			r++
+
+			text := strings.TrimPrefix(line, " ")
+			row := []string{cell(l, d.Parent, a, "---"), fmt.Sprintf("<td>%s</td>", text), cell(r, d.Commit.Hash, b, "+++"), fmt.Sprintf("<td>%s</td>", text)}
+
+			results = append(results, fmt.Sprintf("<tr>%s</tr>", strings.Join(row, "")))
+		}
+	}
+
+	flush()
+
 	return template.HTML(strings.Join(results, "\n"))
 }
 
 func diffstatbodyparser(o overview) template.HTML {
 	var results []string
 	feed := strings.Split(strings.TrimSuffix(o.Body, "\n"), "\n")
+	page := fmt.Sprintf("diff-%s.html", o.Parent)
 
 	for i, line := range feed {
-		if i < len(feed)-1 {
-			// Link files to corresponding diff.
-			columns := strings.Split(line, "|")
-			files := strings.Split(columns[0], "=>")
+		// Link files to corresponding diff, the summary line being last.
+		if i < len(feed)-1 && i < len(o.Files) {
+			f := o.Files[i]
+			l := strings.LastIndex(line, "|")
 
-			a := strings.TrimSpace(files[len(files)-1])
-			b := fmt.Sprintf(`<a href="commit/%s/diff-%s.html#%s">%s</a>`, o.Hash, o.Parent, a, a)
-			l := strings.LastIndex(line, a)
+			if l == -1 {
+				l = len(line)
+			}
 
-			line = line[:l] + strings.Replace(line[l:], a, b, 1)
+			// Brace notation and truncated paths are kept as is for display.
+			name := strings.TrimRight(line[:l], " ")
+			rest := template.HTMLEscapeString(line[len(name):])
+			title := f.Kind()
+
+			if f.Status == "R" || f.Status == "C" {
+				title = fmt.Sprintf("%s from %s, %d%% similar", title, f.OldPath, f.Similarity)
+			}
+
+			line = fmt.Sprintf(`<a href="commit/%s/%s#%s" title="%s">%s</a>%s`, o.Hash, page, template.HTMLEscapeString(f.Path), template.HTMLEscapeString(title), template.HTMLEscapeString(name), rest)
+
+			// Merge commits link files resolved by hand to the combined diff as well.
+			if o.Combined && contains(o.Resolved, f.Path) {
+				line += fmt.Sprintf(` <a href="commit/%s/diff-cc.html#%s" title="combined diff">cc</a>`, o.Hash, template.HTMLEscapeString(f.Path))
+			}
+		} else {
+			line = template.HTMLEscapeString(line)
 		}
 
 		results = append(results, line)
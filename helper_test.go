@@ -3,6 +3,7 @@ package main
 import (
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestContains(t *testing.T) {
@@ -25,3 +26,341 @@ func TestDedupe(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestDiffSplitParser(t *testing.T) {
+	body := "diff --git a/a.txt b/a.txt\n--- a/a.txt\n+++ b/a.txt\n@@ -1,3 +1,2 @@\n same\n-one\n-two\n+three\n"
+	html := string(diffsplitparser(diff{Body: body, Commit: commit{Hash: "b"}, Parent: "a"}))
+
+	// Context plus two paired rows, the second one missing an addition.
+	if n := strings.Count(html, "<tr><td>"); n != 3 {
+		t.Errorf("expected 3 rows, got %d", n)
+	}
+
+	if !strings.Contains(html, `<td><a href="commit/a/a.txt.html#L2">2</a></td><td><del>one</del></td><td><a href="commit/b/a.txt.html#L2">2</a></td><td><ins>three</ins></td>`) {
+		t.Errorf("failed to pair lines: %v", html)
+	}
+
+	if !strings.Contains(html, `<a href="commit/a/a.txt.html#L1">-1</a>,`) {
+		t.Errorf("failed to link hunk header: %v", html)
+	}
+
+	// Single line hunks leave out counts.
+	body = "diff --git a/a.txt b/a.txt\n--- a/a.txt\n+++ b/a.txt\n@@ -1 +1 @@\n-one\n+two\n"
+
+	for _, html := range []string{string(diffsplitparser(diff{Body: body, Commit: commit{Hash: "b"}, Parent: "a"})), string(diffbodyparser(diff{Body: body, Commit: commit{Hash: "b"}, Parent: "a"}))} {
+		if !strings.Contains(html, `@@ <a href="commit/a/a.txt.html#L1">-1</a> <a href="commit/b/a.txt.html#L1">+1</a> @@`) {
+			t.Errorf("failed to link count-less hunk header: %v", html)
+		}
+	}
+}
+
+func TestWordDiff(t *testing.T) {
+	a, b := worddiff("hello world", "hello there world")
+
+	if a != "hello world" || b != "hello <mark>there </mark>world" {
+		t.Errorf("failed to mark words: %q, %q", a, b)
+	}
+
+	a, b = worddiff("x := a &amp;&amp; b", "x := a || b")
+
+	if a != "x := a <mark>&amp;&amp;</mark> b" || b != "x := a <mark>||</mark> b" {
+		t.Errorf("failed to keep entities intact: %q, %q", a, b)
+	}
+
+	// Lines with nothing in common are left alone.
+	if a, b := worddiff("foo", "bar"); a != "foo" || b != "bar" {
+		t.Errorf("expected no marks: %q, %q", a, b)
+	}
+
+	// Oversized lines fall back to whole line highlighting.
+	if a, _ := worddiff("x "+strings.Repeat("a ", 256)+"y", "x "+strings.Repeat("b ", 256)+"y"); strings.Contains(a, "<mark>") {
+		t.Errorf("expected the limit to kick in")
+	}
+}
+
+func TestPatchSplitter(t *testing.T) {
+	body := "diff --git a/a b/a\n@@ -1 +1 @@\n-x\n+y\ndiff --git a/b/c b/b/c\n--- a/b/c\n+++ b/b/c\n"
+	files := patchsplitter(body)
+
+	if len(files) != 2 {
+		t.Fatalf("expected 2 files, got %d", len(files))
+	}
+
+	if files[1].Path != "b/c" || files[1].Index != 2 || files[1].Lines != 3 {
+		t.Errorf("failed to split patch: %+v", files[1])
+	}
+
+	if patchhead(files[0].Body) != "diff --git a/a b/a\n" {
+		t.Errorf("failed to drop hunks: %q", patchhead(files[0].Body))
+	}
+}
+
+func TestDiffBodyParserCombined(t *testing.T) {
+	body := "diff --cc a.txt\n--- a/a.txt\n+++ b/a.txt\n@@@ -1,1 -1,2 +1,3 @@@\n -old\n++new\n  same\n"
+	d := diff{Body: body, Combined: true, Commit: commit{Hash: "c", Parents: []string{"a", "b"}}}
+	html := string(diffbodyparser(d))
+
+	for _, s := range []string{`diff --cc <a id="a.txt">a.txt</a>`, "<del> -old</del>", "<ins>++new</ins>", "\n  same"} {
+		if !strings.Contains(html, s) {
+			t.Errorf("expected %q in %v", s, html)
+		}
+	}
+}
+
+func TestDiffStatBodyParser(t *testing.T) {
+	o := overview{
+		Body:   "{d => x}/b.go | 2 +-\nREADME.md     | 1 +\n2 files changed, 2 insertions(+), 1 deletion(-)",
+		Files:  []stat{{OldPath: "d/b.go", Path: "x/b.go", Similarity: 90, Status: "R"}, {OldPath: "README.md", Path: "README.md", Status: "M"}},
+		Hash:   "b",
+		Parent: "a",
+	}
+
+	html := string(diffstatbodyparser(o))
+
+	if !strings.Contains(html, `<a href="commit/b/diff-a.html#x/b.go" title="renamed from d/b.go, 90% similar">{d =&gt; x}/b.go</a> | 2 +-`) {
+		t.Errorf("failed to link renamed file: %v", html)
+	}
+
+	if !strings.Contains(html, `<a href="commit/b/diff-a.html#README.md" title="modified">README.md</a>     | 1 +`) {
+		t.Errorf("failed to link modified file: %v", html)
+	}
+
+	// Merge commits link files against the parent at hand, adding a link to
+	// the combined diff only for files showing up there.
+	o.Combined, o.Resolved = true, []string{"README.md"}
+	html = string(diffstatbodyparser(o))
+
+	if !strings.Contains(html, `<a href="commit/b/diff-a.html#README.md" title="modified">README.md</a>     | 1 + <a href="commit/b/diff-cc.html#README.md" title="combined diff">cc</a>`) {
+		t.Errorf("failed to link merge commit file to both diffs: %v", html)
+	}
+
+	if strings.Count(html, "diff-cc.html") != 1 {
+		t.Errorf("got %v, want only resolved files linked to the combined diff", html)
+	}
+}
+
+func TestSlugify(t *testing.T) {
+	for in, out := range map[string]string{"Jane Q. Doe": "jane-q-doe", "  Zoë ": "zoë", "!!!": "unknown"} {
+		if got := slugify(in); got != out {
+			t.Errorf("expected %q for %q, got %q", out, in, got)
+		}
+	}
+}
+
+func TestDisambiguate(t *testing.T) {
+	commits := []commit{
+		{Author: author{Email: "jo@example.org", Name: "Jo Doe"}, Date: time.Unix(1, 0), Hash: "a"},
+		{Author: author{Email: "jd@example.org", Name: "jo-doe"}, Date: time.Unix(2, 0), Hash: "b"},
+		{Author: author{Email: "jo@example.org", Name: "Jo Doe"}, Hash: "c"},
+		{Author: author{Email: "x@example.org", Name: "???"}, Hash: "d"},
+		{Author: author{Email: "y@example.org", Name: "!!!"}, Hash: "e"},
+	}
+
+	branches := []branch{{Commits: commits}, {Commits: append([]commit(nil), commits[1:2]...)}}
+
+	disambiguate(branches)
+
+	slugs := make(map[string]string)
+
+	for _, b := range branches {
+		for _, c := range b.Commits {
+			if s, ok := slugs[c.Author.Name]; ok && s != c.Author.Slug() {
+				t.Errorf("%s slugged both %s and %s", c.Author.Name, s, c.Author.Slug())
+			}
+
+			slugs[c.Author.Name] = c.Author.Slug()
+		}
+	}
+
+	if slugs["Jo Doe"] != "jo-doe" {
+		t.Errorf("got %s, want jo-doe kept for the first author", slugs["Jo Doe"])
+	}
+
+	seen := make(map[string]string)
+
+	for name, s := range slugs {
+		if other, ok := seen[s]; ok {
+			t.Errorf("%s and %s share slug %s", name, other, s)
+		}
+
+		if s == "unknown" {
+			t.Errorf("%s left unknown", name)
+		}
+
+		seen[s] = name
+	}
+
+	list := contributors(branches)
+
+	if len(list) != 4 {
+		t.Errorf("got %d contributors, want 4", len(list))
+	}
+
+	// Earliest to commit keeps the plain slug, however branches are ordered.
+	early := commit{Author: author{Email: "jd@example.org", Name: "JO DOE"}, Date: time.Unix(1, 0), Hash: "f"}
+	late := commit{Author: author{Email: "jo@example.org", Name: "Jo Doe"}, Date: time.Unix(2, 0), Hash: "g"}
+
+	for _, order := range [][]commit{{early, late}, {late, early}} {
+		branches := []branch{{Commits: order[:1]}, {Commits: order[1:]}}
+
+		disambiguate(branches)
+
+		for _, b := range branches {
+			if c := b.Commits[0]; c.Hash == "f" && c.Author.Slug() != "jo-doe" {
+				t.Errorf("got %s, want jo-doe for the earliest author", c.Author.Slug())
+			}
+		}
+	}
+}
+
+func TestContributors(t *testing.T) {
+	jo := author{Email: "jo@example.org", Name: "Jo"}
+	al := author{Email: "al@example.org", Name: "Al"}
+
+	commits := []commit{
+		{Author: jo, Date: time.Unix(3, 0), Hash: "c"},
+		{Author: al, Date: time.Unix(2, 0), Hash: "b"},
+		{Author: jo, Date: time.Unix(1, 0), Hash: "a"},
+	}
+
+	// Commits shared across branches count once.
+	list := contributors([]branch{{Commits: commits}, {Commits: commits[1:]}})
+
+	if len(list) != 2 || list[0].Name != "Jo" || len(list[0].Commits) != 2 {
+		t.Fatalf("failed to rank contributors: %+v", list)
+	}
+
+	if !list[0].First.Equal(time.Unix(1, 0)) || !list[0].Last.Equal(time.Unix(3, 0)) {
+		t.Errorf("failed to date contributor: %+v", list[0])
+	}
+}
+
+func TestAnonymize(t *testing.T) {
+	a := author{Email: "Jo@Example.org", Name: "Jo"}
+
+	if b := anonymize(a, privacyOmit); b.Email != "" || b.Hash != "" {
+		t.Errorf("failed to omit email: %+v", b)
+	}
+
+	if b := anonymize(a, privacyObfuscate); b.Email != "Jo at Example dot org" {
+		t.Errorf("failed to obfuscate email: %+v", b)
+	}
+
+	b := anonymize(a, privacyHash)
+
+	if b.Email != "" || len(b.Hash) != 64 || b.Hash != anonymize(author{Email: "jo@example.org"}, privacyHash).Hash {
+		t.Errorf("failed to hash email: %+v", b)
+	}
+
+	if svg := string(b.Identicon()); !strings.HasPrefix(svg, "<svg") || svg != string(identicon(b.Hash)) {
+		t.Errorf("failed to draw identicon: %v", svg)
+	}
+
+	if anonymize(a, "") != a {
+		t.Errorf("expected email to be left alone")
+	}
+}
+
+func TestScrub(t *testing.T) {
+	s := "Signed-off-by: Jo <jo@example.org>\nPing jo@example.org"
+
+	if got := scrub(s, privacyOmit); got != "Signed-off-by: Jo\nPing" {
+		t.Errorf("failed to omit emails: %q", got)
+	}
+
+	if got := scrub(s, privacyObfuscate); got != "Signed-off-by: Jo <jo at example dot org>\nPing jo at example dot org" {
+		t.Errorf("failed to obfuscate emails: %q", got)
+	}
+
+	if got := scrub(s, ""); got != s {
+		t.Errorf("expected text to be left alone: %q", got)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	c := commit{
+		Author:    author{Email: "jo@example.org", Name: "Jo Doe"},
+		Body:      "Fix reported by al@example.org\n\nSigned-off-by: Jo Doe <jo@example.org>",
+		Signature: signature{Key: "ABCD", Signer: "Jo Doe <jo@example.org>", Status: "G"},
+		Subject:   "Thank <al@example.org>",
+		Trailers:  []trailer{{"Signed-off-by", "Jo Doe <jo@example.org>"}, {"Co-authored-by", "Al <al@example.org>"}},
+	}
+
+	for _, mode := range []string{privacyOmit, privacyObfuscate, privacyHash} {
+		r := c.Redact(mode)
+		fields := []string{r.Subject, r.Body, r.Signature.Signer}
+
+		for _, tr := range r.Trailers {
+			fields = append(fields, tr.Value)
+		}
+
+		for _, f := range fields {
+			if strings.Contains(f, "@") {
+				t.Errorf("%s: address left in %q", mode, f)
+			}
+		}
+
+		if !strings.HasPrefix(r.Signature.Signer, "Jo Doe") || !strings.HasPrefix(r.Trailers[1].Value, "Al") {
+			t.Errorf("%s: names lost: %+v", mode, r)
+		}
+	}
+
+	if r := c.Redact(""); r.Body != c.Body || r.Trailers[0] != c.Trailers[0] || r.Signature != c.Signature {
+		t.Errorf("expected commit to be left alone: %+v", r)
+	}
+
+	// Redacting leaves the original trailers be.
+	if c.Redact(privacyOmit); c.Trailers[0].Value != "Jo Doe <jo@example.org>" {
+		t.Errorf("original trailers modified: %+v", c.Trailers)
+	}
+}
+
+func TestCoAuthors(t *testing.T) {
+	c := commit{Trailers: []trailer{{"Signed-off-by", "Jo <jo@example.org>"}, {"co-authored-by", "Al <al@example.org>"}}}
+	list := c.CoAuthors()
+
+	if len(list) != 1 || list[0].Name != "Al" || list[0].Email != "al@example.org" {
+		t.Errorf("failed to parse co-authors: %+v", list)
+	}
+
+	jo := author{Email: "jo@example.org", Name: "Jo"}
+	al := author{Email: "al@example.org", Name: "Al"}
+	c.Author = jo
+	c.Hash = "a"
+
+	credited := contributors([]branch{{Commits: []commit{c, {Author: al, Hash: "b"}}}})
+
+	for _, r := range credited {
+		if r.Name == "Al" && len(r.CoAuthored) != 1 {
+			t.Errorf("failed to credit co-author: %+v", r)
+		}
+	}
+}
+
+func TestAutolink(t *testing.T) {
+	rules, err := linkrules([]string{`#(\d+)=https://example.org/issues/$1`, `JIRA-\d+=https://jira.example.org/browse/$0`, "(oops"})
+
+	if err == nil || len(rules) != 2 {
+		t.Errorf("expected invalid rule to be reported and skipped: %v", err)
+	}
+
+	hashes := []string{"1234567890abcdef1234567890abcdef12345678", "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef"}
+	got := string(autolink("Fixes #12 & JIRA-7, see https://example.org/a?b=c. Reverts deadbeef, not cafebabe. Bumps 1234567 to 1234567890a.", rules, hashes))
+	want := `Fixes <a href="https://example.org/issues/12">#12</a> &amp; <a href="https://jira.example.org/browse/JIRA-7">JIRA-7</a>, see <a href="https://example.org/a?b=c">https://example.org/a?b=c</a>. Reverts <a href="commit/deadbeefdeadbeefdeadbeefdeadbeefdeadbeef/">deadbeef</a>, not cafebabe. Bumps 1234567 to <a href="commit/1234567890abcdef1234567890abcdef12345678/">1234567890a</a>.`
+
+	if got != want {
+		t.Errorf("failed to autolink:\n%v\n%v", got, want)
+	}
+}
+
+func TestDays(t *testing.T) {
+	list, err := days(" 90,30,,90")
+
+	if err != nil || len(list) != 3 || list[0] != 30 || list[1] != 90 || list[2] != 0 {
+		t.Errorf("failed to parse days: %v, %v", list, err)
+	}
+
+	if _, err := days("soon"); err == nil {
+		t.Errorf("expected an error")
+	}
+}
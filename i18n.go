@@ -0,0 +1,274 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Holds message catalogs per locale, English messages doubling as keys.
+//
+//go:embed locales
+var catalogs embed.FS
+
+// Pages are in English unless configured otherwise.
+const defaultLocale = "en"
+
+// Themes may add to or override catalogs in a subdirectory by this name.
+const themeLocales = "locales"
+
+// Matches month and weekday names in time layouts.
+var datenames = regexp.MustCompile(`January|Monday|Jan|Mon`)
+
+// Holds translations for a locale along with date layouts and names.
+type catalog struct {
+	// Maps layout names, "long" say, onto Go time layouts.
+	Dates       map[string]string `json:"dates"`
+	Days        []string          `json:"days"`
+	Messages    map[string]string `json:"messages"`
+	Months      []string          `json:"months"`
+	ShortDays   []string          `json:"shortdays"`
+	ShortMonths []string          `json:"shortmonths"`
+}
+
+// Helps layer catalogs, regional variants on top of base languages say.
+func (c *catalog) merge(o catalog) {
+	for k, v := range o.Dates {
+		c.Dates[k] = v
+	}
+
+	for k, v := range o.Messages {
+		c.Messages[k] = v
+	}
+
+	for _, p := range []struct {
+		dst *[]string
+		src []string
+	}{
+		{&c.Days, o.Days},
+		{&c.Months, o.Months},
+		{&c.ShortDays, o.ShortDays},
+		{&c.ShortMonths, o.ShortMonths},
+	} {
+		if len(p.src) > 0 {
+			*p.dst = p.src
+		}
+	}
+}
+
+// Describes how pages read: language, date formats, and time zone.
+type locale struct {
+	catalog
+	// Tags the language for `lang` attributes, "pt-BR" say.
+	Lang string
+	// Converts times for display, left as committed if nil.
+	zone *time.Location
+}
+
+// Helps set up a locale using embedded catalogs and any a theme brings
+// along, regional variants falling back to the base language and English.
+func loadLocale(theme string, lang string, zone string) (*locale, error) {
+	if lang == "" {
+		lang = defaultLocale
+	}
+
+	l := &locale{
+		catalog: catalog{Dates: make(map[string]string), Messages: make(map[string]string)},
+		Lang:    lang,
+	}
+
+	if zone != "" {
+		loc, err := time.LoadLocation(zone)
+
+		if err != nil {
+			return nil, fmt.Errorf("unable to load time zone: %v", err)
+		}
+
+		l.zone = loc
+	}
+
+	embedded, err := fs.Sub(catalogs, "locales")
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to read catalogs: %v", err)
+	}
+
+	sources := []fs.FS{embedded}
+
+	if theme != "" {
+		sources = append(sources, os.DirFS(filepath.Join(theme, themeLocales)))
+	}
+
+	base, _, _ := strings.Cut(lang, "-")
+	names := []string{defaultLocale}
+
+	for _, name := range []string{base, lang} {
+		if !contains(names, name) {
+			names = append(names, name)
+		}
+	}
+
+	var found bool
+
+	for _, name := range names {
+		for _, fsys := range sources {
+			var c catalog
+
+			bs, err := fs.ReadFile(fsys, name+".json")
+
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+
+			if err != nil {
+				return nil, fmt.Errorf("unable to read catalog: %v", err)
+			}
+
+			if err := json.Unmarshal(bs, &c); err != nil {
+				return nil, fmt.Errorf("unable to parse catalog %s: %v", name, err)
+			}
+
+			found = found || name == base || name == lang
+
+			l.merge(c)
+		}
+	}
+
+	if !found {
+		return nil, fmt.Errorf("no catalog for locale %s", lang)
+	}
+
+	return l, nil
+}
+
+// Lists locale specific functions for templates.
+func (l *locale) funcs() template.FuncMap {
+	return template.FuncMap{
+		"ago":        l.ago,
+		"bytesize":   l.bytesize,
+		"churnchart": l.churnchart,
+		"date":       l.date,
+		"in":         l.in,
+		"lang": func() string {
+			return l.Lang
+		},
+		"monthlychart": l.monthlychart,
+		"plural":       l.plural,
+		"punchcard":    l.punchcard,
+		"t":            l.translate,
+		"weeklychart":  l.weeklychart,
+	}
+}
+
+// Helps look up messages, formatting any arguments into them. Messages
+// missing from the catalog are used as is.
+func (l *locale) translate(key string, args ...interface{}) string {
+	msg, ok := l.Messages[key]
+
+	if !ok || msg == "" {
+		msg = key
+	}
+
+	if len(args) == 0 {
+		return msg
+	}
+
+	return fmt.Sprintf(msg, args...)
+}
+
+// Helps format times using a named layout from the catalog, "long" say, or
+// else a Go time layout, in the display time zone and with month and day
+// names spelled out in the locale's language.
+func (l *locale) date(layout string, t time.Time) string {
+	return l.format(layout, l.local(t))
+}
+
+// Helps format times as they are, for dates already bucketed in UTC say.
+func (l *locale) format(layout string, t time.Time) string {
+	if named, ok := l.Dates[layout]; ok {
+		layout = named
+	}
+
+	var sb strings.Builder
+
+	last := 0
+
+	for _, m := range datenames.FindAllStringIndex(layout, -1) {
+		sb.WriteString(t.Format(layout[last:m[0]]))
+		sb.WriteString(l.datename(layout[m[0]:m[1]], t))
+		last = m[1]
+	}
+
+	sb.WriteString(t.Format(layout[last:]))
+
+	return sb.String()
+}
+
+// Helps convert times into the display time zone, leaving them as committed
+// if no zone is set.
+func (l *locale) local(t time.Time) time.Time {
+	if l.zone != nil {
+		return t.In(l.zone)
+	}
+
+	return t
+}
+
+// Helps templates formatting times on their own convert them into the display
+// time zone, `in .Date` say, or a named one, `in "Asia/Tokyo" .Date`, leaving
+// them as they are if the zone is unknown.
+func (l *locale) in(args ...interface{}) (time.Time, error) {
+	switch len(args) {
+	case 1:
+		if t, ok := args[0].(time.Time); ok {
+			return l.local(t), nil
+		}
+	case 2:
+		zone, ok := args[0].(string)
+		t, isTime := args[1].(time.Time)
+
+		if !ok || !isTime {
+			break
+		}
+
+		loc, err := time.LoadLocation(zone)
+
+		if err != nil {
+			return t, nil
+		}
+
+		return t.In(loc), nil
+	}
+
+	return time.Time{}, fmt.Errorf("in: want a time, optionally preceded by a zone name, got %v", args)
+}
+
+func (l *locale) datename(token string, t time.Time) string {
+	var names []string
+	var i int
+
+	switch token {
+	case "January":
+		names, i = l.Months, int(t.Month())-1
+	case "Jan":
+		names, i = l.ShortMonths, int(t.Month())-1
+	case "Monday":
+		names, i = l.Days, int(t.Weekday())
+	case "Mon":
+		names, i = l.ShortDays, int(t.Weekday())
+	}
+
+	if i < len(names) {
+		return names[i]
+	}
+
+	return t.Format(token)
+}
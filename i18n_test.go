@@ -0,0 +1,198 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadLocale(t *testing.T) {
+	theme := t.TempDir()
+
+	if err := os.MkdirAll(filepath.Join(theme, themeLocales), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(theme, themeLocales, "de-AT.json"), []byte(`{"messages": {"Home": "Startseite, Servus"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := loadLocale(theme, "de-AT", "")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		got  string
+		want string
+	}{
+		{l.Lang, "de-AT"},
+		{l.translate("Home"), "Startseite, Servus"},
+		{l.translate("File tree"), "Dateibaum"},
+		{l.translate("%d commits total", 3), "3 Commits insgesamt"},
+		{l.translate("Not in any catalog"), "Not in any catalog"},
+		{l.date("iso", time.Date(2006, 1, 2, 0, 0, 0, 0, time.UTC)), "2006-01-02"},
+	}
+
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("got %q, want %q", tt.got, tt.want)
+		}
+	}
+
+	for _, lang := range []string{"", "en", "en-GB", "fr"} {
+		if _, err := loadLocale("", lang, ""); err != nil {
+			t.Errorf("%q: %v", lang, err)
+		}
+	}
+
+	if _, err := loadLocale("", "xx", ""); err == nil {
+		t.Error("unknown locale accepted")
+	}
+
+	if _, err := loadLocale("", "en", "Mars/Base"); err == nil {
+		t.Error("unknown time zone accepted")
+	}
+}
+
+func TestLocaleDate(t *testing.T) {
+	at := time.Date(2006, 1, 2, 22, 4, 5, 0, time.UTC)
+
+	en, err := loadLocale("", "en", "")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	de, err := loadLocale("", "de", "Europe/Berlin")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		l      *locale
+		layout string
+		want   string
+	}{
+		{en, "long", "Jan. 02 '06 22:04:05"},
+		{en, "short", "01/02/06 22:04"},
+		{en, "Monday, January 2", "Monday, January 2"},
+		{de, "long", "02. Jan. 2006 23:04:05"},
+		{de, "day", "02.01.06"},
+		{de, "Mon, Monday 2. January 2006 15:04 MST", "Mo., Montag 2. Januar 2006 23:04 CET"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.l.date(tt.layout, at); got != tt.want {
+			t.Errorf("%s %q: got %q, want %q", tt.l.Lang, tt.layout, got, tt.want)
+		}
+	}
+
+	if got, err := de.in(at); err != nil || got.Hour() != 23 || !got.Equal(at) {
+		t.Errorf("got %v, %v, want 23:00 in Berlin", got, err)
+	}
+
+	if got, err := en.in(at); err != nil || got != at {
+		t.Errorf("got %v, %v without a zone, want time unchanged", got, err)
+	}
+
+	if got, err := de.in("Asia/Tokyo", at); err != nil || got.Hour() != 7 || !got.Equal(at) {
+		t.Errorf("got %v, %v, want 07:00 in Tokyo", got, err)
+	}
+
+	if got, err := de.in("Nowhere/Special", at); err != nil || got != at {
+		t.Errorf("got %v, %v for unknown zone, want time unchanged", got, err)
+	}
+
+	if _, err := de.in("Asia/Tokyo"); err == nil {
+		t.Error("zone without a time accepted")
+	}
+}
+
+func TestCatalogs(t *testing.T) {
+	keys := make(map[string]bool)
+	tkey := regexp.MustCompile(`{{-?\s*t "([^"]+)"`)
+
+	err := fs.WalkDir(embedded, "templates", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		bs, err := fs.ReadFile(embedded, p)
+
+		for _, m := range tkey.FindAllStringSubmatch(string(bs), -1) {
+			keys[m[1]] = true
+		}
+
+		return err
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Signature states are translated by their spelled out name.
+	for _, s := range strings.Split("GBUXYREN", "") {
+		keys[signature{Status: s}.String()] = true
+	}
+
+	// So are words helpers put out.
+	for _, k := range []string{"in the future", "just now", "byte", "bytes"} {
+		keys[k] = true
+	}
+
+	for _, u := range agounits {
+		keys[u.one] = true
+		keys[u.other] = true
+	}
+
+	entries, err := fs.ReadDir(catalogs, "locales")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, e := range entries {
+		lang := strings.TrimSuffix(e.Name(), ".json")
+		l, err := loadLocale("", lang, "")
+
+		if err != nil {
+			t.Errorf("%s: %v", lang, err)
+
+			continue
+		}
+
+		for _, name := range []string{"day", "iso", "long", "short"} {
+			if _, ok := l.Dates[name]; !ok {
+				t.Errorf("%s: missing %s date layout", lang, name)
+			}
+		}
+
+		if lang == defaultLocale {
+			continue
+		}
+
+		for k := range keys {
+			if _, ok := l.Messages[k]; !ok {
+				t.Errorf("%s: missing %q", lang, k)
+			}
+		}
+
+		// Charts name days in full and short.
+		if len(l.Days) == 0 || len(l.ShortDays) == 0 {
+			t.Errorf("%s: missing day names", lang)
+		}
+
+		for _, names := range [][]string{l.Days, l.ShortDays, l.Months, l.ShortMonths} {
+			if n := len(names); n != 0 && n != 7 && n != 12 {
+				t.Errorf("%s: got %d day or month names", lang, n)
+			}
+		}
+	}
+}
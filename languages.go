@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"html/template"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Maps file extensions to language names, overridable using the -d flag.
+var extensions = map[string]string{
+	".c":     "C",
+	".cc":    "C++",
+	".cpp":   "C++",
+	".cs":    "C#",
+	".css":   "CSS",
+	".go":    "Go",
+	".h":     "C",
+	".hpp":   "C++",
+	".html":  "HTML",
+	".java":  "Java",
+	".js":    "JavaScript",
+	".json":  "JSON",
+	".kt":    "Kotlin",
+	".lua":   "Lua",
+	".md":    "Markdown",
+	".mjs":   "JavaScript",
+	".php":   "PHP",
+	".pl":    "Perl",
+	".py":    "Python",
+	".rb":    "Ruby",
+	".rs":    "Rust",
+	".scss":  "SCSS",
+	".sh":    "Shell",
+	".sql":   "SQL",
+	".swift": "Swift",
+	".tmpl":  "Go Template",
+	".toml":  "TOML",
+	".ts":    "TypeScript",
+	".tsx":   "TypeScript",
+	".txt":   "Text",
+	".xml":   "XML",
+	".yaml":  "YAML",
+	".yml":   "YAML",
+	".zig":   "Zig",
+}
+
+// Maps shebang interpreters to language names for scripts lacking an extension.
+var interpreters = map[string]string{
+	"bash":    "Shell",
+	"node":    "JavaScript",
+	"perl":    "Perl",
+	"php":     "PHP",
+	"python":  "Python",
+	"python3": "Python",
+	"ruby":    "Ruby",
+	"sh":      "Shell",
+	"zsh":     "Shell",
+}
+
+// Adds up per language figures.
+type language struct {
+	Bytes int64
+	Files int
+	Lines int
+	Name  string
+}
+
+// Color returns a stable color for the language.
+func (l language) Color() string {
+	h := fnv.New32a()
+	h.Write([]byte(l.Name))
+
+	return fmt.Sprintf("#%06x", h.Sum32()&0xffffff)
+}
+
+// Lists languages by size, largest first.
+type breakdown []language
+
+// Bytes returns the total size across languages.
+func (b breakdown) Bytes() int64 {
+	var n int64
+
+	for _, l := range b {
+		n += l.Bytes
+	}
+
+	return n
+}
+
+// Lines returns the total number of lines across languages.
+func (b breakdown) Lines() int {
+	var n int
+
+	for _, l := range b {
+		n += l.Lines
+	}
+
+	return n
+}
+
+// Share returns a language's size as a percentage of the total.
+func (b breakdown) Share(l language) float64 {
+	if total := b.Bytes(); total > 0 {
+		return float64(l.Bytes) * 100 / float64(total)
+	}
+
+	return 0
+}
+
+// Bar draws the breakdown as a single stacked bar.
+func (b breakdown) Bar() template.HTML {
+	var sb strings.Builder
+	var x float64
+
+	sb.WriteString(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 100 1" preserveAspectRatio="none" width="100%" height="8">`)
+
+	for _, l := range b {
+		w := b.Share(l)
+
+		fmt.Fprintf(&sb, `<rect x="%.2f" y="0" width="%.2f" height="1" fill="%s"><title>%s %.1f%%</title></rect>`, x, w, l.Color(), template.HTMLEscapeString(l.Name), w)
+
+		x += w
+	}
+
+	sb.WriteString("</svg>")
+
+	return template.HTML(sb.String())
+}
+
+// Helps parse `extension=name` language mappings on top of the built in ones.
+func dialects(specs []string) map[string]string {
+	results := make(map[string]string)
+
+	for k, v := range extensions {
+		results[k] = v
+	}
+
+	for _, spec := range specs {
+		ext, name, ok := strings.Cut(spec, "=")
+
+		if !ok {
+			continue
+		}
+
+		if !strings.HasPrefix(ext, ".") {
+			ext = fmt.Sprintf(".%s", ext)
+		}
+
+		results[strings.ToLower(ext)] = name
+	}
+
+	return results
+}
+
+// Helps figure out a file's language going by extension, then shebang.
+func detect(path string, head string, mapping map[string]string) string {
+	if name, ok := mapping[strings.ToLower(filepath.Ext(path))]; ok {
+		return name
+	}
+
+	if strings.HasPrefix(head, "#!") {
+		w := strings.Fields(strings.TrimPrefix(head, "#!"))
+
+		// Skip over `env` and its flags to reach the interpreter.
+		for len(w) > 0 && (filepath.Base(w[0]) == "env" || strings.HasPrefix(w[0], "-")) {
+			w = w[1:]
+		}
+
+		if len(w) > 0 {
+			if name, ok := interpreters[filepath.Base(w[0])]; ok {
+				return name
+			}
+		}
+	}
+
+	return "Other"
+}
+
+// Helps sort per language figures into a breakdown.
+func tallylanguages(m map[string]*language) breakdown {
+	var results breakdown
+
+	for _, l := range m {
+		results = append(results, *l)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Bytes != results[j].Bytes {
+			return results[i].Bytes > results[j].Bytes
+		}
+
+		return results[i].Name < results[j].Name
+	})
+
+	return results
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	mapping := dialects([]string{"tmpl=Templates", ".GO=Golang"})
+
+	for _, c := range []struct{ path, head, want string }{
+		{"main.go", "", "Golang"},
+		{"page.html.tmpl", "", "Templates"},
+		{"script", "#!/usr/bin/env -S python3 -u", "Python"},
+		{"run", "#!/bin/sh", "Shell"},
+		{"COPYING", "", "Other"},
+	} {
+		if got := detect(c.path, c.head, mapping); got != c.want {
+			t.Errorf("expected %q for %q, got %q", c.want, c.path, got)
+		}
+	}
+}
+
+func TestBreakdown(t *testing.T) {
+	b := tallylanguages(map[string]*language{
+		"Go":    {Bytes: 300, Files: 3, Name: "Go"},
+		"Shell": {Bytes: 100, Files: 1, Name: "Shell"},
+	})
+
+	if b[0].Name != "Go" || b.Bytes() != 400 || b.Share(b[0]) != 75 {
+		t.Errorf("failed to break down languages: %+v", b)
+	}
+
+	if bar := string(b.Bar()); strings.Count(bar, "<rect") != 2 {
+		t.Errorf("failed to draw bar: %v", bar)
+	}
+}
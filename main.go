@@ -1,16 +1,15 @@
 package main
 
 import (
-	_ "embed"
-	"encoding/json"
 	"flag"
 	"fmt"
+	"html/template"
 	"io"
 	"log"
 	"net/url"
 	"os"
 	"path/filepath"
-	"reflect"
+	"sort"
 	"strings"
 	"text/tabwriter"
 )
@@ -18,15 +17,51 @@ import (
 // EMPTY is git's magic empty tree hash.
 const EMPTY = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
 
-//go:embed page.html.tmpl
-var tpl string
+// Describes a subcommand, `build` being the default.
+type command struct {
+	about string
+	name  string
+	run   func(args []string)
+	usage string
+}
+
+var commands []command
 
 func init() {
+	commands = []command{
+		{"Generate a static archive", "build", build, "[<options>] <path>"},
+		{"Serve a generated archive locally", "serve", serve, "[<options>] <path>"},
+		{"Write out the default page templates", "export-template", exportTemplate, "[<options>] <path>"},
+		{"Write out a starter config file", "init", initialize, "[<options>] <path>"},
+		{"Remove generated pages and objects", "clean", clean, "[<options>] <path>"},
+		{"Check generated pages for broken links", "verify", verify, "[<options>] <path>"},
+		{"Check templates against sample pages", "check-template", checkTemplate, "[<options>] [<template>]"},
+	}
+
 	// Override default usage output.
 	flag.Usage = func() {
-		// Print usage example ahead of listing default options.
-		fmt.Fprintln(flag.CommandLine.Output(), "usage:", os.Args[0], "[<options>] <path>")
-		flag.PrintDefaults()
+		out := flag.CommandLine.Output()
+
+		// Print usage example ahead of listing available commands.
+		fmt.Fprintln(out, "usage:", os.Args[0], "[<command>] [<options>] <path>")
+		fmt.Fprintln(out, "commands:")
+
+		tab := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+
+		for _, c := range commands {
+			fmt.Fprintf(tab, "  %s\t%s\n", c.name, c.about)
+		}
+
+		tab.Flush()
+
+		fmt.Fprintln(out, "Defaults to build, use", os.Args[0], "help <command> for command specific options.")
+
+		// List build options too, build being what runs without a command.
+		fs, _, _ := buildset(defaults())
+		fs.SetOutput(out)
+
+		fmt.Fprintln(out, "build options:")
+		printflags(fs)
 	}
 
 	// Swap default logger timestamps for a custom prefix.
@@ -35,123 +70,211 @@ func init() {
 }
 
 func main() {
-	opt := &options{
-		config: ".jimmy.json",
+	args := os.Args[1:]
+
+	if len(args) > 0 {
+		switch args[0] {
+		case "help", "-h", "-help", "--help":
+			if len(args) > 1 {
+				// Have the command print its own usage.
+				lookup(args[1]).run([]string{"-h"})
+			}
+
+			flag.Usage()
+
+			return
+		}
+
+		if c, ok := find(args[0]); ok {
+			c.run(args[1:])
+
+			return
+		}
 	}
 
-	// NOTE: Flags need to match each option key's first letter.
-	flag.StringVar(&opt.Name, "n", "Jimbo", "Project title")
-	flag.StringVar(&opt.Source, "s", "", "Source repository")
-	flag.Var(&opt.Branches, "b", "Target branches")
-	flag.StringVar(&opt.Template, "t", "", "Page template")
-	flag.BoolVar(&opt.Quiet, "q", false, "Be quiet")
-	flag.BoolVar(&opt.Export, "e", false, "Export default template")
-	flag.BoolVar(&opt.Force, "f", false, "Force rebuild")
-	flag.Parse()
+	// Keep `gtx [<options>] <path>` working.
+	build(args)
+}
 
-	if opt.Quiet {
-		log.SetOutput(io.Discard)
+// Helps look up commands by name.
+func find(name string) (command, bool) {
+	for _, c := range commands {
+		if c.name == name {
+			return c, true
+		}
 	}
 
-	cwd, err := os.Getwd()
+	return command{}, false
+}
 
-	if err != nil {
-		log.Fatalf("unable to get current working directory: %v", err)
+// Helps look up commands by name, bailing out if none match.
+func lookup(name string) command {
+	c, ok := find(name)
+
+	if !ok {
+		fmt.Fprintln(flag.CommandLine.Output(), "unknown command:", name)
+		flag.Usage()
+		os.Exit(2)
 	}
 
-	// Defaults to the current working directory if no argument present.
-	dir := flag.Arg(0)
+	return c
+}
 
-	// Make sure `dir` is an absolute path.
-	if ok := filepath.IsAbs(dir); !ok {
-		dir = filepath.Join(cwd, dir)
+// Helps set up command specific flag sets, usage output included.
+func flagset(name string) *flag.FlagSet {
+	c, _ := find(name)
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage:", os.Args[0], c.name, c.usage)
+		fmt.Fprintln(fs.Output(), c.about)
+		printflags(fs)
 	}
 
-	if opt.Export {
-		if err := os.WriteFile(filepath.Join(dir, "page.html.tmpl"), []byte(tpl), 0644); err != nil {
-			log.Fatalf("unable to export default template: %v", err)
+	return fs
+}
+
+// Helps print flags much like `PrintDefaults` does, long forms listed next
+// to the short flags they stand in for.
+func printflags(fs *flag.FlagSet) {
+	var groups [][]*flag.Flag
+
+	seen := make(map[flag.Value]int)
+
+	fs.VisitAll(func(f *flag.Flag) {
+		if i, ok := seen[f.Value]; ok {
+			groups[i] = append(groups[i], f)
+
+			return
 		}
 
-		log.Printf("done exporting default template")
+		seen[f.Value] = len(groups)
+		groups = append(groups, []*flag.Flag{f})
+	})
 
-		return
+	for _, g := range groups {
+		sort.Slice(g, func(i, j int) bool {
+			return len(g[i].Name) < len(g[j].Name)
+		})
 	}
 
-	if opt.Template != "" {
-		bs, err := os.ReadFile(opt.Template)
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i][0].Name < groups[j][0].Name
+	})
 
-		if err != nil {
-			log.Printf("unable to read template: %v", err)
+	for _, g := range groups {
+		var names []string
+
+		for _, f := range g {
+			names = append(names, "-"+f.Name)
+		}
+
+		kind, usage := flag.UnquoteUsage(g[0])
+		line := "  " + strings.Join(names, ", ")
+
+		if kind != "" {
+			line += " " + kind
+		}
+
+		if len(line) <= 4 {
+			line += "\t"
 		} else {
-			tpl = string(bs)
+			line += "\n    \t"
 		}
+
+		line += strings.ReplaceAll(usage, "\n", "\n    \t")
+
+		switch def := g[0].DefValue; {
+		case def == "" || def == "0" || def == "false":
+		case kind == "string":
+			line += fmt.Sprintf(" (default %q)", def)
+		default:
+			line += fmt.Sprintf(" (default %v)", def)
+		}
+
+		fmt.Fprintln(fs.Output(), line)
+	}
+}
+
+// Helps resolve the target directory, the current working directory by default.
+func target(arg string) string {
+	if filepath.IsAbs(arg) {
+		return arg
 	}
 
-	// Attempt to read saved settings.
-	cnf, err := os.ReadFile(filepath.Join(dir, opt.config))
+	cwd, err := os.Getwd()
 
 	if err != nil {
-		log.Printf("unable to read config file: %v", err)
+		log.Fatalf("unable to get current working directory: %v", err)
 	}
 
-	// Create a separate options instance for reading config file values into.
-	store := *opt
+	return filepath.Join(cwd, arg)
+}
+
+// Helps set up build flags, those only making sense on the command line included.
+func buildset(opt *options) (*flag.FlagSet, *bool, *bool) {
+	fs := flagset("build")
+	buildflags(fs, opt)
+
+	printConfig := fs.Bool("print-config", false, "Print effective settings as JSON and quit")
+	save := fs.Bool("save", false, "Save effective settings to the config file")
 
-	// Need deep copy the underlying slice types.
-	store.Branches = append(store.Branches, opt.Branches...)
+	return fs, printConfig, save
+}
 
-	// If a config file exists and an option has not been set, override default to match.
-	if err := json.Unmarshal(cnf, &store); err != nil {
-		log.Printf("unable to parse config file: %v", err)
+func build(args []string) {
+	opt := defaults()
+
+	fs, printConfig, save := buildset(opt)
+	fs.Parse(args)
+
+	if opt.Quiet {
+		log.SetOutput(io.Discard)
 	}
 
-	// Collect flags provided.
-	flagset := make(map[string]bool)
+	// Defaults to the current working directory if no argument present.
+	dir := target(fs.Arg(0))
 
-	// NOTE: These need to come before the output directory argument.
-	flag.Visit(func(f *flag.Flag) {
-		flagset[f.Name] = true
-	})
+	configure(fs, opt, dir)
 
-	ref := reflect.ValueOf(store)
-	tab := tabwriter.NewWriter(log.Writer(), 0, 0, 0, '.', 0)
-
-	flag.VisitAll(func(f *flag.Flag) {
-		// Attempt to source settings from config file, then override flag defaults.
-		if !flagset[f.Name] {
-			v := ref.FieldByNameFunc(func(n string) bool {
-				return strings.HasPrefix(strings.ToLower(n), f.Name)
-			})
-
-			// Don't ask.
-			if s, ok := v.Interface().(manyflag); ok {
-				for _, b := range s {
-					flag.Set(f.Name, b)
-				}
-			} else {
-				// This has the welcome side effect of magically overriding `opt` fields.
-				flag.Set(f.Name, v.String())
-			}
+	if *printConfig {
+		if err := opt.print(os.Stdout); err != nil {
+			log.Fatalf("unable to print options: %v", err)
 		}
 
-		fmt.Fprintf(tab, "gtx: -%s \t%s\t: %v\n", f.Name, f.Usage, f.Value)
-	})
+		return
+	}
 
-	tab.Flush()
+	if opt.Quiet {
+		log.SetOutput(io.Discard)
+	}
 
-	// The repo flag is required at this point.
-	if ok := filepath.IsAbs(opt.Source); ok {
-		// Option considered repo-like if it contains a hidden `.git` dir.
-		if _, err := os.Stat(filepath.Join(opt.Source, ".git")); os.IsNotExist(err) {
-			flag.Usage()
-			os.Exit(1)
-		}
-	} else {
-		// Allow for URL-looking non-local repos.
-		if _, err := url.ParseRequestURI(opt.Source); err != nil {
-			flag.Usage()
-			os.Exit(1)
-		}
+	// Either a single template file or a directory of overrides on top of
+	// the theme's templates.
+	t, err := loadTemplates(opt.Theme, opt.Template, funcMap())
+
+	if err != nil {
+		log.Fatalf("unable to load templates: %v", err)
+	}
+
+	l, err := loadLocale(opt.Theme, opt.Locale, opt.Zone)
+
+	if err != nil {
+		log.Fatalf("unable to set up locale: %v", err)
+	}
+
+	t = t.clone(l.funcs())
+
+	switch opt.Privacy {
+	case "", privacyOmit, privacyObfuscate, privacyHash:
+	default:
+		log.Fatalf("unknown email privacy mode: %s", opt.Privacy)
+	}
+
+	// The repo flag is required at this point, unless archiving many projects.
+	if len(opt.Projects) == 0 && !repolike(opt.Source) {
+		fs.Usage()
+		os.Exit(1)
 	}
 
 	// Make sure `dir` exists.
@@ -159,9 +282,34 @@ func main() {
 		log.Fatalf("unable to create output directory: %v", err)
 	}
 
-	// Save current settings for future use.
-	if err := opt.save(dir); err != nil {
-		log.Fatalf("unable to save options: %v", err)
+	// Settings are only saved when asked to, before paths are made absolute.
+	if *save {
+		if err := opt.save(dir); err != nil {
+			log.Fatalf("unable to save options: %v", err)
+		}
+	}
+
+	// Git runs from within the clone, so local files need absolute paths.
+	for _, p := range []*string{&opt.Mailmap, &opt.Keyring} {
+		if *p != "" {
+			*p = target(*p)
+		}
+	}
+
+	// Themes bring along stylesheets and the like, linked to by original name.
+	if opt.Theme != "" {
+		a, err := copyAssets(opt.Theme, dir, opt.Fingerprint)
+
+		if err != nil {
+			log.Fatalf("unable to set up theme: %v", err)
+		}
+
+		t = t.clone(template.FuncMap{"asset": a.url})
+	}
+
+	// Broken templates would otherwise make for truncated pages.
+	if err := t.check(); err != nil {
+		log.Fatalf("unable to apply template: %v", err)
 	}
 
 	tmp, err := os.MkdirTemp("", "")
@@ -172,25 +320,178 @@ func main() {
 
 	defer os.RemoveAll(tmp)
 
-	pro := NewProject(dir, tmp, opt)
+	if len(opt.Projects) == 0 {
+		if _, err := generate(dir, tmp, opt, t, nil); err != nil {
+			log.Fatal(err)
+		}
+
+		return
+	}
+
+	var clones []string
+	var listings []listing
+
+	slugs := projectSlugs(opt.Projects, opt)
+
+	for i, r := range opt.Projects {
+		o := r.options(opt)
+
+		// Local sources are taken relative to the working directory.
+		if _, err := os.Stat(o.Source); err == nil {
+			o.Source = target(o.Source)
+		}
+
+		if !repolike(o.Source) {
+			log.Printf("skipping project %s: not a repository: %s", o.Name, o.Source)
+
+			continue
+		}
+
+		slug := slugs[i]
+		repo := filepath.Join(tmp, slug)
+
+		if slug != slugify(o.Name) {
+			log.Printf("project %s archived under %s, its name being taken", o.Name, slug)
+		}
+
+		if err := os.MkdirAll(repo, 0755); err != nil {
+			log.Fatalf("unable to create clone directory: %v", err)
+		}
+
+		log.Printf("processing project: %s", o.Name)
+
+		// Each project gets a copy of theme assets to link to from below its base.
+		if o.Theme != "" {
+			if _, err := copyAssets(o.Theme, filepath.Join(dir, slug), o.Fingerprint); err != nil {
+				log.Fatalf("unable to set up theme: %v", err)
+			}
+		}
+
+		l, err := generate(filepath.Join(dir, slug), repo, o, t, clones)
+
+		if err != nil {
+			log.Printf("skipping project %s: %v", o.Name, err)
+
+			continue
+		}
+
+		l.Path = fmt.Sprintf("%s/", slug)
+
+		clones = append(clones, repo)
+		listings = append(listings, l)
+	}
+
+	// Extra pages show up on the site index too.
+	extras, err := readPages(opt.Pages)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	t = t.clone(template.FuncMap{
+		"pages": func() []extra {
+			return extras
+		},
+	})
+
+	if err := writeExtraPages(dir, t, opt.Name, extras); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := writeSiteIndex(dir, t, opt.Name, listings); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// Helps tell if `s` looks like a local repo or a URL to one.
+func repolike(s string) bool {
+	if filepath.IsAbs(s) {
+		// Option considered repo-like if it contains a hidden `.git` dir.
+		_, err := os.Stat(filepath.Join(s, ".git"))
+
+		return !os.IsNotExist(err)
+	}
+
+	// Allow for URL-looking non-local repos.
+	_, err := url.ParseRequestURI(s)
+
+	return err == nil
+}
+
+// Archives a single project into `dir` using `tmp` for cloning into,
+// returning a summary for listing on site indices.
+func generate(dir string, tmp string, opt *options, t templates, clones []string) (listing, error) {
+	extras, err := readPages(opt.Pages)
+
+	if err != nil {
+		return listing{}, err
+	}
+
+	t = t.clone(template.FuncMap{
+		"pages": func() []extra {
+			return extras
+		},
+	})
+
+	pro := NewProject(dir, tmp, opt, t)
+	pro.clones = clones
 
 	// Create base directories.
 	if err := pro.init(); err != nil {
-		log.Fatalf("unable to initialize output directory: %v", err)
+		return listing{}, fmt.Errorf("unable to initialize output directory: %v", err)
 	}
 
 	// Clone target repo.
 	if err := pro.save(); err != nil {
-		log.Fatalf("unable to set up repo: %v", err)
+		return listing{}, fmt.Errorf("unable to set up repo: %v", err)
 	}
 
-	branches, err := branchFilter(tmp, opt)
+	pro.fetchNotes()
+
+	// Blobs shared across branch tips and tags are read once.
+	seen := make(sniffs)
+
+	branches, err := branchFilter(tmp, opt, seen)
 
 	if err != nil {
-		log.Fatalf("unable to filter branches: %v", err)
+		return listing{}, fmt.Errorf("unable to filter branches: %v", err)
 	}
 
+	// Authors need telling apart before any page links to them.
+	disambiguate(branches)
+
+	tags, err := tagParser(tmp, opt, seen)
+
+	if err != nil {
+		log.Printf("unable to list tags: %v", err)
+	}
+
+	// Read the license off of the first branch tip, what the index leads with.
+	rev := "HEAD"
+
+	if len(branches) > 0 && len(branches[0].Commits) > 0 {
+		rev = branches[0].Commits[0].Hash
+	}
+
+	meta := pro.metadata(rev)
+
 	pro.updateBranches(branches)
 	pro.writePages(branches)
-	pro.writeMainIndex(branches)
+	pro.writeAuthorPages(branches)
+	pro.writeStatsPage(branches)
+	pro.writeMainIndex(branches, tags, meta)
+	pro.writeExport(branches, tags, meta)
+
+	if err := writeExtraPages(dir, t, opt.Name, extras); err != nil {
+		log.Printf("unable to write pages: %v", err)
+	}
+
+	l := listing{
+		metadata: meta,
+		Name:     opt.Name,
+		Source:   opt.Source,
+		Updated:  lastUpdated(branches),
+	}
+
+	return l, nil
 }
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Helps build an archive of `repo` into a fresh directory, returning its path.
+func archive(t *testing.T, repo string, opt *options) string {
+	t.Helper()
+
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	tpl, err := loadTemplates("", "", funcMap())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opt.Source = repo
+	dir := t.TempDir()
+
+	if _, err := generate(dir, t.TempDir(), opt, tpl, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	return dir
+}
+
+func TestPrivacy(t *testing.T) {
+	repo := gitinit(t)
+
+	gitcommit(t, repo, map[string][]byte{"a.txt": []byte("a\n")}, "First")
+	gitcommit(t, repo, map[string][]byte{"a.txt": []byte("b\n")}, strings.Join([]string{
+		"Second, thanks to al@example.org",
+		"",
+		"Reported by <al@example.org>.",
+		"",
+		"Co-authored-by: Al <al@example.org>",
+		"Signed-off-by: Jo Doe <jo@example.org>",
+	}, "\n"))
+
+	for _, mode := range []string{privacyOmit, privacyObfuscate, privacyHash} {
+		opt := defaults()
+		opt.Privacy = mode
+
+		dir := archive(t, repo, opt)
+		pages, err := filepath.Glob(filepath.Join(dir, "commit", "*", "*.html"))
+
+		if err != nil || len(pages) == 0 {
+			t.Fatalf("%s: no commit pages: %v", mode, err)
+		}
+
+		pages = append(pages, filepath.Join(dir, exportName))
+
+		for _, p := range pages {
+			bs, err := os.ReadFile(p)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if m := emailaddress.FindString(string(bs)); m != "" {
+				rel, _ := filepath.Rel(dir, p)
+				t.Errorf("%s: address %q left in %s", mode, m, rel)
+			}
+		}
+	}
+}
+
+func TestNestedObjects(t *testing.T) {
+	repo := gitinit(t)
+
+	gitcommit(t, repo, map[string][]byte{
+		"a.txt":          []byte("a\n"),
+		"sub/a.go":       []byte("package sub\n"),
+		"sub/deep/b.txt": []byte("b\n"),
+	}, "First")
+
+	dir := archive(t, repo, defaults())
+	pages, err := filepath.Glob(filepath.Join(dir, "commit", "*", "sub", "*.html"))
+
+	if err != nil || len(pages) == 0 {
+		t.Fatalf("no nested object pages: %v", err)
+	}
+
+	err = filepath.WalkDir(filepath.Join(dir, "commit"), func(p string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(p) != ".html" {
+			return err
+		}
+
+		bs, err := os.ReadFile(p)
+
+		if err != nil {
+			return err
+		}
+
+		rel, _ := filepath.Rel(dir, p)
+
+		for _, link := range brokenlinks(dir, p, string(bs), make(map[string]map[string]bool)) {
+			t.Errorf("broken link in %s: %s", rel, link)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+}
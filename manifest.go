@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Names the list of files a build wrote into directories it shares with files
+// placed by hand, `assets` and `page` say, so only those get cleared later.
+const manifest = ".gtx-manifest"
+
+// Helps read back the files listed in `dir`'s manifest, relative to `dir`.
+// Entries reaching outside of `dir` are left out.
+func readManifest(dir string) ([]string, error) {
+	bs, err := os.ReadFile(filepath.Join(dir, manifest))
+
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to read manifest: %v", err)
+	}
+
+	var results []string
+
+	scanner := bufio.NewScanner(bytes.NewReader(bs))
+
+	for scanner.Scan() {
+		p := filepath.Clean(filepath.FromSlash(scanner.Text()))
+
+		if p == "." || filepath.IsAbs(p) || p == ".." || strings.HasPrefix(p, ".."+string(filepath.Separator)) {
+			continue
+		}
+
+		results = append(results, p)
+	}
+
+	return results, scanner.Err()
+}
+
+// Helps list the files written into `dir`, relative to it, for clearing next
+// time around.
+func writeManifest(dir string, files []string) error {
+	var sb strings.Builder
+
+	for _, f := range files {
+		fmt.Fprintln(&sb, filepath.ToSlash(f))
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, manifest), []byte(sb.String()), 0644); err != nil {
+		return fmt.Errorf("unable to write manifest: %v", err)
+	}
+
+	return nil
+}
+
+// Helps remove the files listed in `dir`'s manifest along with the manifest
+// itself, pruning directories left empty, `dir` included. Anything else in
+// there is left alone.
+func sweep(dir string) error {
+	files, err := readManifest(dir)
+
+	if err != nil {
+		return err
+	}
+
+	dirs := []string{dir}
+
+	for _, f := range files {
+		p := filepath.Join(dir, f)
+
+		if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("unable to remove %s: %v", f, err)
+		}
+
+		for d := filepath.Dir(p); d != dir; d = filepath.Dir(d) {
+			dirs = append(dirs, d)
+		}
+	}
+
+	if err := os.Remove(filepath.Join(dir, manifest)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("unable to remove manifest: %v", err)
+	}
+
+	// Deepest first, so parents empty out in turn.
+	sort.Slice(dirs, func(i, j int) bool {
+		return len(dirs[i]) > len(dirs[j])
+	})
+
+	for _, d := range dedupe(dirs) {
+		// Fails on directories holding files placed by hand, which is fine.
+		os.Remove(d)
+	}
+
+	return nil
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Lists file names checked for license texts, in order.
+var licenseFiles = []string{"LICENSE", "LICENSE.md", "LICENSE.txt", "LICENCE", "COPYING", "COPYING.md", "UNLICENSE"}
+
+// Matches SPDX identifiers against phrases found in the license text, in
+// order, more specific licenses coming first. A license matches if all of its
+// `title` phrases appear in the opening lines, all of its `has` phrases appear
+// anywhere, and none of its `not` phrases do. Licenses mentioning one another
+// in passing, GPL-3.0 naming the AGPL say, are told apart by title.
+var licenses = []struct {
+	id    string
+	title []string
+	has   []string
+	not   []string
+}{
+	{"AGPL-3.0", []string{"gnu affero general public license", "version 3"}, nil, nil},
+	{"LGPL-3.0", []string{"gnu lesser general public license", "version 3"}, nil, nil},
+	{"LGPL-2.1", []string{"gnu lesser general public license", "version 2.1"}, nil, nil},
+	{"GPL-3.0", []string{"gnu general public license", "version 3"}, nil, nil},
+	{"GPL-2.0", []string{"gnu general public license", "version 2"}, nil, nil},
+	{"Apache-2.0", []string{"apache license", "version 2.0"}, nil, nil},
+	{"MPL-2.0", []string{"mozilla public license version 2.0"}, nil, nil},
+	{"MIT", nil, []string{"permission is hereby granted free of charge to any person obtaining a copy"}, nil},
+	{"ISC", nil, []string{"permission to use copy modify and or distribute this software for any purpose with or without fee is hereby granted", "the above copyright notice and this permission notice appear in all copies"}, nil},
+	{"0BSD", nil, []string{"permission to use copy modify and or distribute this software for any purpose with or without fee is hereby granted"}, nil},
+	{"BSD-3-Clause", nil, []string{"redistribution and use in source and binary forms", "neither the name"}, nil},
+	{"BSD-2-Clause", nil, []string{"redistribution and use in source and binary forms"}, []string{"neither the name"}},
+	{"Unlicense", nil, []string{"this is free and unencumbered software released into the public domain"}, nil},
+}
+
+// Counts the non-blank lines license titles are looked for in, leaving room
+// for a copyright line or two ahead of the title proper.
+const titleLines = 5
+
+// Matches runs of anything other than letters, digits, and dots for normalizing license texts.
+var nonword = regexp.MustCompile(`[^a-z0-9.]+`)
+
+// Git's stock description, meaning none was ever set.
+const unnamed = "Unnamed repository"
+
+// Describes a project beyond its name and source.
+type metadata struct {
+	Description string
+	Homepage    string
+	// Holds the SPDX identifier of the detected license, "Other" if unrecognized.
+	License string
+	Topics  []string
+}
+
+// Helps collect project metadata, settings taking precedence over what is
+// found in the repo. The license is read off of revision `rev`.
+func (p *project) metadata(rev string) metadata {
+	m := metadata{
+		Description: p.options.Description,
+		Homepage:    p.options.Homepage,
+		Topics:      p.options.Topics,
+	}
+
+	if m.Description == "" {
+		m.Description = describe(p.options.Source)
+	}
+
+	for _, name := range licenseFiles {
+		cmd := exec.Command("git", "cat-file", "blob", rev+":"+name)
+		cmd.Dir = p.repo
+
+		out, err := cmd.Output()
+
+		if err != nil {
+			continue
+		}
+
+		m.License = license(string(out))
+
+		break
+	}
+
+	return m
+}
+
+// Helps read gitweb style `description` files from local repos, bare or not.
+func describe(source string) string {
+	if !filepath.IsAbs(source) {
+		return ""
+	}
+
+	for _, p := range []string{filepath.Join(source, ".git", "description"), filepath.Join(source, "description")} {
+		bs, err := os.ReadFile(p)
+
+		if err != nil {
+			continue
+		}
+
+		if s := strings.TrimSpace(string(bs)); !strings.HasPrefix(s, unnamed) {
+			return s
+		}
+
+		return ""
+	}
+
+	return ""
+}
+
+// Helps figure out which license a text is, going by telltale phrases.
+func license(text string) string {
+	var head []string
+
+	for _, line := range strings.Split(text, "\n") {
+		if len(head) == titleLines {
+			break
+		}
+
+		if strings.TrimSpace(line) != "" {
+			head = append(head, line)
+		}
+	}
+
+	title := normalize(strings.Join(head, "\n"))
+	text = normalize(text)
+
+	for _, l := range licenses {
+		if matches(title, l.title, nil) && matches(text, l.has, l.not) {
+			return l.id
+		}
+	}
+
+	return "Other"
+}
+
+func matches(text string, has []string, not []string) bool {
+	for _, s := range has {
+		if !strings.Contains(text, normalize(s)) {
+			return false
+		}
+	}
+
+	for _, s := range not {
+		if strings.Contains(text, normalize(s)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Helps compare texts regardless of case, punctuation, and line wrapping.
+func normalize(s string) string {
+	s = nonword.ReplaceAllString(strings.ToLower(s), " ")
+
+	// Dots only count within version numbers.
+	s = strings.ReplaceAll(s, ". ", " ")
+
+	return strings.TrimRight(strings.TrimSpace(s), ".")
+}
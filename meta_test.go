@@ -0,0 +1,107 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLicense(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"MIT License\n\nPermission is hereby granted, free of charge, to any person\nobtaining a copy of this software", "MIT"},
+		{"Apache License\nVersion 2.0, January 2004", "Apache-2.0"},
+		{"GNU GENERAL PUBLIC LICENSE\nVersion 3, 29 June 2007", "GPL-3.0"},
+		{"GNU GENERAL PUBLIC LICENSE\nVersion 2, June 1991", "GPL-2.0"},
+		{"GNU LESSER GENERAL PUBLIC LICENSE\nVersion 2.1, February 1999\n\n[This is the first released version of the Lesser GPL. It also counts\nas the successor of the GNU Library Public License, version 2]", "LGPL-2.1"},
+		{"GNU AFFERO GENERAL PUBLIC LICENSE\nVersion 3, 19 November 2007\n\nthe GNU General Public License", "AGPL-3.0"},
+		{"Permission to use, copy, modify, and/or distribute this software for any\npurpose with or without fee is hereby granted, provided that the above\ncopyright notice and this permission notice appear in all copies.", "ISC"},
+		{"Permission to use, copy, modify, and/or distribute this software for any\npurpose with or without fee is hereby granted.", "0BSD"},
+		{"Redistribution and use in source and binary forms, with or without\nmodification... 3. Neither the name of the copyright holder", "BSD-3-Clause"},
+		{"Redistribution and use in source and binary forms, with or without\nmodification, are permitted", "BSD-2-Clause"},
+		{"This is free and unencumbered software released into the public domain.", "Unlicense"},
+		{"All rights reserved.", "Other"},
+	}
+
+	for _, tt := range tests {
+		if got := license(tt.text); got != tt.want {
+			t.Errorf("license(%.30q) = %s, want %s", tt.text, got, tt.want)
+		}
+	}
+
+	// Full texts mention other licenses in passing.
+	dir := "/usr/share/common-licenses"
+	texts := map[string]string{
+		"Apache-2.0": "Apache-2.0",
+		"GPL-2":      "GPL-2.0",
+		"GPL-3":      "GPL-3.0",
+		"LGPL-2.1":   "LGPL-2.1",
+		"LGPL-3":     "LGPL-3.0",
+		"MPL-2.0":    "MPL-2.0",
+	}
+
+	for name, want := range texts {
+		bs, err := os.ReadFile(filepath.Join(dir, name))
+
+		if err != nil {
+			t.Logf("skipping %s: %v", name, err)
+
+			continue
+		}
+
+		if got := license(string(bs)); got != want {
+			t.Errorf("%s: got %s, want %s", name, got, want)
+		}
+
+		if name != "GPL-3" {
+			continue
+		}
+
+		// No AGPL text ships alongside, its title atop the GPL's body will do,
+		// the latter naming the AGPL in section 13 too.
+		_, body, _ := strings.Cut(string(bs), "\n")
+		text := "GNU AFFERO GENERAL PUBLIC LICENSE\n" + body
+
+		if got := license(text); got != "AGPL-3.0" {
+			t.Errorf("AGPL-3.0: got %s", got)
+		}
+
+		// Title lines may follow a copyright notice.
+		if got := license("Copyright (c) 2022 Jimbo\n\n" + string(bs)); got != want {
+			t.Errorf("%s after a copyright line: got %s, want %s", name, got, want)
+		}
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	dir := t.TempDir()
+
+	if got := describe(dir); got != "" {
+		t.Errorf("got %q for missing file, want none", got)
+	}
+
+	p := filepath.Join(dir, "description")
+
+	if err := os.WriteFile(p, []byte("Unnamed repository; edit this file 'description' to name the repository.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := describe(dir); got != "" {
+		t.Errorf("got %q for stock description, want none", got)
+	}
+
+	if err := os.WriteFile(p, []byte("Static git archives\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := describe(dir); got != "Static git archives" {
+		t.Errorf("got %q, want Static git archives", got)
+	}
+
+	if got := describe("https://example.com/repo.git"); got != "" {
+		t.Errorf("got %q for remote source, want none", got)
+	}
+}
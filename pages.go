@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Matches the first top level heading in HTML fragments for titling pages.
+var htmlheading = regexp.MustCompile(`(?is)<h1[^>]*>(.*?)</h1>`)
+
+// Matches tags for stripping out of titles.
+var htmltag = regexp.MustCompile(`<[^>]*>`)
+
+// Describes a page written by hand, "About" or "How to contribute" say, as
+// opposed to generated from the repo.
+type extra struct {
+	Body  template.HTML
+	Slug  string
+	Title string
+}
+
+// URL returns the page's location relative to the page base.
+func (e extra) URL() string {
+	return fmt.Sprintf("page/%s/", e.Slug)
+}
+
+// Helps read Markdown and HTML fragments out of `dir` in order of file name,
+// each page titled after its first top level heading or else its file name.
+func readPages(dir string) ([]extra, error) {
+	if dir == "" {
+		return nil, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to list pages: %v", err)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	var results []extra
+
+	taken := make(map[string]bool)
+
+	for _, e := range entries {
+		ext := strings.ToLower(filepath.Ext(e.Name()))
+
+		if e.IsDir() || (ext != ".md" && ext != ".markdown" && ext != ".html" && ext != ".htm") {
+			continue
+		}
+
+		bs, err := os.ReadFile(filepath.Join(dir, e.Name()))
+
+		if err != nil {
+			return nil, fmt.Errorf("unable to read page: %v", err)
+		}
+
+		name := strings.TrimSuffix(e.Name(), filepath.Ext(e.Name()))
+		p := extra{Slug: slugify(name)}
+
+		// Number off pages slugifying alike, `about.md` next to `about.html` say.
+		for i, base := 2, p.Slug; taken[p.Slug]; i++ {
+			p.Slug = fmt.Sprintf("%s-%d", base, i)
+		}
+
+		taken[p.Slug] = true
+
+		if ext == ".md" || ext == ".markdown" {
+			p.Body = markdown(string(bs))
+		} else {
+			// HTML fragments are written by the archive's owner and trusted as such.
+			p.Body = template.HTML(bs)
+		}
+
+		if m := htmlheading.FindStringSubmatch(string(p.Body)); m != nil {
+			p.Title = strings.TrimSpace(html.UnescapeString(htmltag.ReplaceAllString(m[1], "")))
+		}
+
+		if p.Title == "" {
+			p.Title = strings.ReplaceAll(name, "-", " ")
+		}
+
+		results = append(results, p)
+	}
+
+	return results, nil
+}
+
+// Helps write out pages written by hand under `page/` in `base`, clearing
+// any left over from earlier runs.
+func writeExtraPages(base string, t templates, name string, extras []extra) error {
+	root := filepath.Join(base, "page")
+
+	if err := sweep(root); err != nil {
+		return fmt.Errorf("unable to clear pages: %v", err)
+	}
+
+	var written []string
+
+	for _, e := range extras {
+		dir := filepath.Join(root, e.Slug)
+
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("unable to create page directory: %v", err)
+		}
+
+		f, err := os.Create(filepath.Join(dir, "index.html"))
+
+		if err != nil {
+			return fmt.Errorf("unable to create page: %v", err)
+		}
+
+		page := page{
+			Base: "../../",
+			Data: pageView{
+				Page:    e,
+				Project: name,
+			},
+			Title: strings.Join([]string{name, e.Title}, ": "),
+		}
+
+		err = t.render(f, kindPage, page)
+		f.Close()
+
+		if err != nil {
+			return fmt.Errorf("unable to apply template: %v", err)
+		}
+
+		written = append(written, filepath.Join(e.Slug, "index.html"))
+	}
+
+	if len(written) == 0 {
+		return nil
+	}
+
+	return writeManifest(root, written)
+}
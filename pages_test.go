@@ -0,0 +1,133 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadPages(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"about.md":               "Intro\n\n# About *Jimbo*\n\nHello.",
+		"how-to-contribute.html": "<h1 class=\"title\">How to <em>contribute</em> &amp; more</h1>\n<p>Send patches.</p>",
+		"untitled.md":            "No heading here.",
+		"notes.txt":              "ignored",
+	}
+
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := readPages(dir)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		slug  string
+		title string
+	}{
+		{"about", "About Jimbo"},
+		{"how-to-contribute", "How to contribute & more"},
+		{"untitled", "untitled"},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d pages, want %d", len(got), len(want))
+	}
+
+	for i, w := range want {
+		if got[i].Slug != w.slug || got[i].Title != w.title {
+			t.Errorf("got %s titled %q, want %s titled %q", got[i].Slug, got[i].Title, w.slug, w.title)
+		}
+	}
+
+	if !strings.Contains(string(got[0].Body), "<h1>About <em>Jimbo</em></h1>") {
+		t.Errorf("got %q, want Markdown rendered", got[0].Body)
+	}
+
+	if got[1].URL() != "page/how-to-contribute/" {
+		t.Errorf("got %q, want page/how-to-contribute/", got[1].URL())
+	}
+
+	if pages, err := readPages(""); err != nil || pages != nil {
+		t.Errorf("got %v, %v for no pages directory", pages, err)
+	}
+
+	if _, err := readPages(filepath.Join(dir, "missing")); err == nil {
+		t.Error("missing pages directory accepted")
+	}
+
+	// Pages slugifying alike are numbered off in file name order.
+	dir = t.TempDir()
+
+	for _, name := range []string{"About.md", "about-2.md", "about.html"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err = readPages(dir)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, slug := range []string{"about", "about-2", "about-3"} {
+		if i >= len(got) || got[i].Slug != slug {
+			t.Errorf("page %d: got %+v, want %s", i, got, slug)
+		}
+	}
+}
+
+func TestWriteExtraPages(t *testing.T) {
+	base := t.TempDir()
+	stale := filepath.Join(base, "page", "stale")
+	kept := filepath.Join(base, "page", "kept.css")
+
+	tpl, err := loadTemplates("", "", funcMap())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeExtraPages(base, tpl, "Jimbo", []extra{{Slug: "stale", Title: "Stale"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Files placed by hand are left alone.
+	if err := os.WriteFile(kept, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	extras := []extra{{Body: "<p>Hello.</p>", Slug: "about", Title: "About"}}
+
+	if err := writeExtraPages(base, tpl, "Jimbo", extras); err != nil {
+		t.Fatal(err)
+	}
+
+	bs, err := os.ReadFile(filepath.Join(base, "page", "about", "index.html"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"<title>Jimbo: About</title>", "<p>Hello.</p>", `<base href="../../">`} {
+		if !strings.Contains(string(bs), want) {
+			t.Errorf("page missing %q", want)
+		}
+	}
+
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Error("stale page left over")
+	}
+
+	if _, err := os.Stat(kept); err != nil {
+		t.Errorf("hand placed file removed: %v", err)
+	}
+}
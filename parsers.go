@@ -4,15 +4,18 @@ import (
 	"bufio"
 	"bytes"
 	"fmt"
+	"io"
 	"log"
+	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 )
 
 // Goes through list of branches and returns those that match whitelist.
-func branchFilter(repo string, options *options) ([]branch, error) {
+func branchFilter(repo string, options *options, seen sniffs) ([]branch, error) {
 	cmd := exec.Command("git", "branch", "-a")
 	cmd.Dir = repo
 
@@ -52,16 +55,41 @@ func branchFilter(repo string, options *options) ([]branch, error) {
 		}
 	}
 
+	notes := notesParser(repo, options.Notes)
+
 	for k, v := range m {
 		if v {
 			// TODO: Try a goroutine?
-			commits, err := commitParser(k, repo, options.Name)
+			commits, err := commitParser(k, repo, options)
 
 			if err != nil {
 				continue
 			}
 
-			b[k] = branch{commits, k, options.Name}
+			for i, c := range commits {
+				for _, n := range notes[c.Hash] {
+					n.Body = scrub(n.Body, options.Privacy)
+					commits[i].Notes = append(commits[i].Notes, n)
+				}
+			}
+
+			var languages breakdown
+
+			// Languages are figured out at the branch tip.
+			if len(commits) > 0 {
+				languages, err = languageParser(commits[0].Tree, repo, dialects(options.Languages), seen)
+
+				if err != nil {
+					log.Printf("unable to break down languages: %v", err)
+				}
+			}
+
+			b[k] = branch{
+				Commits:   commits,
+				Languages: languages,
+				Name:      k,
+				Project:   options.Name,
+			}
 		}
 	}
 
@@ -75,12 +103,37 @@ func branchFilter(repo string, options *options) ([]branch, error) {
 	return results, nil
 }
 
-func commitParser(b string, repo string, name string) ([]commit, error) {
-	fst := strings.Join([]string{"%H", "%P", "%s", "%aN", "%aE", "%aD", "%h"}, SEP)
+func commitParser(b string, repo string, options *options) ([]commit, error) {
+	// Trailers are unit separated to keep each commit on a single line.
+	fields := []string{"%H", "%P", "%s", "%aN", "%aE", "%aD", "%h", "%cN", "%cE", "%cD", "%(trailers:only,unfold,separator=%x1f)"}
+
+	var args []string
+	var env []string
+
+	// Mailmap entries supplied locally take precedence over those in the repo.
+	if options.Mailmap != "" {
+		args = append(args, "-c", fmt.Sprintf("mailmap.file=%s", options.Mailmap))
+	}
+
+	// Signatures are only checked against keys supplied locally.
+	if options.Keyring != "" {
+		fields = append(fields, "%G?", "%GS", "%GK")
+
+		if fi, err := os.Stat(options.Keyring); err == nil && fi.IsDir() {
+			env = append(env, fmt.Sprintf("GNUPGHOME=%s", options.Keyring))
+		} else {
+			args = append(args, "-c", fmt.Sprintf("gpg.ssh.allowedSignersFile=%s", options.Keyring))
+		}
+	}
+
+	fst := strings.Join(fields, SEP)
 	ref := fmt.Sprintf("origin/%s", b)
 
-	cmd := exec.Command("git", "log", fmt.Sprintf("--format=%s", fst), ref)
+	args = append(args, "log", fmt.Sprintf("--format=%s", fst), ref)
+
+	cmd := exec.Command("git", args...)
 	cmd.Dir = repo
+	cmd.Env = append(os.Environ(), env...)
 
 	out, err := cmd.Output()
 
@@ -104,6 +157,18 @@ func commitParser(b string, repo string, name string) ([]commit, error) {
 			parents = strings.Split(data[1], " ")
 		}
 
+		var resolved []string
+
+		if len(parents) > 1 {
+			var err error
+
+			resolved, err = combinedPathParser(h, repo)
+
+			if err != nil {
+				log.Printf("unable to list combined diff paths: %s", err)
+			}
+		}
+
 		for _, parent := range parents {
 			diffstat, err := diffStatParser(h, parent, repo)
 
@@ -113,10 +178,40 @@ func commitParser(b string, repo string, name string) ([]commit, error) {
 				continue
 			}
 
-			history = append(history, overview{diffstat, h, parent})
+			files, err := fileStatParser(h, parent, repo)
+
+			if err != nil {
+				log.Printf("unable to stat files against parent: %s", err)
+
+				continue
+			}
+
+			history = append(history, overview{
+				Body:     diffstat,
+				Combined: len(parents) > 1,
+				Files:    files,
+				Hash:     h,
+				Parent:   parent,
+				Resolved: resolved,
+			})
 		}
 
-		a := author{data[4], data[3]}
+		// Totals go against the first parent, or the empty tree for root commits.
+		var totals summary
+
+		if len(history) > 0 {
+			totals = summarize(history[0].Files)
+		} else if len(parents) == 0 {
+			files, err := fileStatParser(h, EMPTY, repo)
+
+			if err != nil {
+				log.Printf("unable to stat files against empty tree: %s", err)
+			}
+
+			totals = summarize(files)
+		}
+
+		a := anonymize(author{Email: data[4], Name: data[3]}, options.Privacy)
 
 		date, err := time.Parse("Mon, 2 Jan 2006 15:04:05 -0700", data[5])
 
@@ -126,6 +221,22 @@ func commitParser(b string, repo string, name string) ([]commit, error) {
 			continue
 		}
 
+		committer := anonymize(author{Email: data[8], Name: data[7]}, options.Privacy)
+
+		committed, err := time.Parse("Mon, 2 Jan 2006 15:04:05 -0700", data[9])
+
+		if err != nil {
+			log.Printf("unable to parse commit date: %s", err)
+
+			continue
+		}
+
+		var sig signature
+
+		if len(data) > 13 {
+			sig = signature{Key: data[13], Signer: data[12], Status: data[11]}
+		}
+
 		body, err := bodyParser(h, repo)
 
 		if err != nil {
@@ -143,20 +254,26 @@ func commitParser(b string, repo string, name string) ([]commit, error) {
 		}
 
 		c := commit{
-			Abbr:    data[6],
-			Author:  a,
-			Body:    body,
-			Branch:  b,
-			Date:    date,
-			Hash:    h,
-			History: history,
-			Parents: parents,
-			Project: name,
-			Subject: data[2],
-			Tree:    tree,
+			Abbr:      data[6],
+			Author:    a,
+			Body:      body,
+			Branch:    b,
+			Committed: committed,
+			Committer: committer,
+			Date:      date,
+			Hash:      h,
+			History:   history,
+			Parents:   parents,
+			Project:   options.Name,
+			Signature: sig,
+			Subject:   data[2],
+			Totals:    totals,
+			Trailers:  trailerParser(data[10]),
+			Tree:      tree,
 		}
 
-		results = append(results, c)
+		// Addresses show up in free text just as well.
+		results = append(results, c.Redact(options.Privacy))
 	}
 
 	if err := scanner.Err(); err != nil {
@@ -191,8 +308,34 @@ func treeParser(h string, repo string) ([]object, error) {
 	return results, nil
 }
 
+// Lists paths showing up in a merge commit's combined diff, which leaves out
+// files taken as they were from either side.
+func combinedPathParser(h string, repo string) ([]string, error) {
+	cmd := exec.Command("git", "diff-tree", "-p", "--cc", "-M", "-C", "--no-commit-id", h)
+	cmd.Dir = repo
+
+	out, err := cmd.Output()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var results []string
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(nil, 1<<20)
+
+	for scanner.Scan() {
+		if m := ccanchor.FindStringSubmatch(scanner.Text()); m != nil {
+			results = append(results, m[1])
+		}
+	}
+
+	return results, scanner.Err()
+}
+
 func diffStatParser(h, parent string, repo string) (string, error) {
-	cmd := exec.Command("git", "diff", "--stat", fmt.Sprintf("%s..%s", parent, h))
+	cmd := exec.Command("git", "diff", "--stat", "-M", "-C", fmt.Sprintf("%s..%s", parent, h))
 	cmd.Dir = repo
 
 	out, err := cmd.Output()
@@ -219,6 +362,507 @@ func diffStatParser(h, parent string, repo string) (string, error) {
 	return strings.Join(results, "\n"), nil
 }
 
+// Collects per file change records with rename and copy detection on.
+func fileStatParser(h, parent string, repo string) ([]stat, error) {
+	rng := fmt.Sprintf("%s..%s", parent, h)
+
+	cmd := exec.Command("git", "diff", "-z", "-M", "-C", "--raw", "--no-abbrev", rng)
+	cmd.Dir = repo
+
+	out, err := cmd.Output()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var results []stat
+
+	// Blob hashes to look up sizes for, old and new sides alternating.
+	var blobs []string
+
+	// Entries are NUL separated, renames and copies list both paths.
+	feed := strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00")
+
+	for i := 0; i < len(feed); i++ {
+		// Looks like `:100644 100644 <old> <new> M`.
+		meta := strings.Fields(strings.TrimPrefix(feed[i], ":"))
+
+		if len(meta) != 5 || i+1 >= len(feed) {
+			return nil, fmt.Errorf("malformed raw diff entry: %q", feed[i])
+		}
+
+		s := stat{Status: meta[4][:1]}
+		s.Similarity, _ = strconv.Atoi(meta[4][1:])
+		s.OldPath = feed[i+1]
+		s.Path = feed[i+1]
+
+		i++
+
+		if s.Status == "R" || s.Status == "C" {
+			if i+1 >= len(feed) {
+				return nil, fmt.Errorf("malformed raw diff entry: %q", feed[i])
+			}
+
+			s.Path = feed[i+1]
+
+			i++
+		}
+
+		blobs = append(blobs, meta[2], meta[3])
+		results = append(results, s)
+	}
+
+	sizes, err := blobSizeParser(blobs, repo)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range results {
+		results[i].OldSize = sizes[blobs[2*i]]
+		results[i].NewSize = sizes[blobs[2*i+1]]
+	}
+
+	cmd = exec.Command("git", "diff", "-z", "-M", "-C", "--numstat", rng)
+	cmd.Dir = repo
+
+	out, err = cmd.Output()
+
+	if err != nil {
+		return nil, err
+	}
+
+	feed = strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00")
+
+	// Both listings come in the same order.
+	for i, n := 0, 0; i < len(feed) && n < len(results); i, n = i+1, n+1 {
+		columns := strings.SplitN(feed[i], "\t", 3)
+
+		if len(columns) != 3 {
+			return nil, fmt.Errorf("malformed numstat entry: %q", feed[i])
+		}
+
+		// Renames and copies have paths follow in separate fields.
+		if columns[2] == "" {
+			i += 2
+		}
+
+		// Binary files show up as dashes.
+		results[n].Bin = columns[0] == "-"
+		results[n].Adds, _ = strconv.Atoi(columns[0])
+		results[n].Dels, _ = strconv.Atoi(columns[1])
+	}
+
+	return results, nil
+}
+
+// Looks up blob sizes in bytes in one go, missing or null hashes are left out.
+func blobSizeParser(blobs []string, repo string) (map[string]int64, error) {
+	results := make(map[string]int64)
+
+	var input bytes.Buffer
+
+	for _, b := range dedupe(blobs) {
+		if strings.Trim(b, "0") != "" {
+			fmt.Fprintln(&input, b)
+		}
+	}
+
+	if input.Len() == 0 {
+		return results, nil
+	}
+
+	cmd := exec.Command("git", "cat-file", "--batch-check")
+	cmd.Dir = repo
+	cmd.Stdin = &input
+
+	out, err := cmd.Output()
+
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+
+	for scanner.Scan() {
+		// Looks like `<hash> blob <size>` or `<hash> missing`.
+		w := strings.Fields(scanner.Text())
+
+		if len(w) != 3 {
+			continue
+		}
+
+		n, err := strconv.ParseInt(w[2], 10, 64)
+
+		if err != nil {
+			continue
+		}
+
+		results[w[0]] = n
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// Lists tags, newest first, along with a language breakdown for each.
+func tagParser(repo string, options *options, seen sniffs) ([]tag, error) {
+	// The dereferenced object name is only set for annotated tags.
+	cmd := exec.Command("git", "for-each-ref", "--sort=-creatordate", "--format=%(refname:short) %(objectname) %(*objectname)", "refs/tags")
+	cmd.Dir = repo
+
+	out, err := cmd.Output()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var results []tag
+	mapping := dialects(options.Languages)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+
+	for scanner.Scan() {
+		w := strings.Fields(scanner.Text())
+
+		if len(w) < 2 {
+			continue
+		}
+
+		t := tag{Hash: w[len(w)-1], Name: w[0]}
+
+		tree, err := treeParser(t.Hash, repo)
+
+		if err != nil {
+			log.Printf("unable to parse tag tree: %s", err)
+
+			continue
+		}
+
+		t.Languages, err = languageParser(tree, repo, mapping, seen)
+
+		if err != nil {
+			log.Printf("unable to break down languages: %v", err)
+		}
+
+		results = append(results, t)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// Adds up file counts, lines, and bytes per language across a tree. Blobs
+// are read once per build, `seen` carrying over what earlier trees turned up,
+// and binaries found by their null bytes are left out whatever their name.
+func languageParser(tree []object, repo string, mapping map[string]string, seen sniffs) (breakdown, error) {
+	var blobs []string
+
+	for _, obj := range tree {
+		if _, ok := seen[obj.Hash]; !ok {
+			blobs = append(blobs, obj.Hash)
+		}
+	}
+
+	if err := sniffParser(dedupe(blobs), repo, seen); err != nil {
+		return nil, err
+	}
+
+	m := make(map[string]*language)
+
+	for _, obj := range tree {
+		blob, ok := seen[obj.Hash]
+
+		// Submodules and the like come back missing.
+		if !ok || blob.binary {
+			continue
+		}
+
+		name := detect(obj.Path, blob.line, mapping)
+
+		l, ok := m[name]
+
+		if !ok {
+			l = &language{Name: name}
+			m[name] = l
+		}
+
+		l.Bytes += blob.size
+		l.Files++
+		l.Lines += blob.lines
+	}
+
+	return tallylanguages(m), nil
+}
+
+// Git looks this far into blobs for null bytes when telling binaries apart.
+const sniffLimit = 8000
+
+// Holds what reading a blob through tells about it.
+type sniff struct {
+	binary bool
+	// Holds the first line, for a shebang say.
+	line  string
+	lines int
+	size  int64
+}
+
+// Caches sniffed blobs per hash, for sharing across trees of a repo.
+type sniffs map[string]sniff
+
+// Helps read blobs in a single pass, noting size, line count, and first
+// line of each into `seen`, telling apart binaries by their null bytes.
+// Missing blobs are left out.
+func sniffParser(blobs []string, repo string, seen sniffs) error {
+	if len(blobs) == 0 {
+		return nil
+	}
+
+	cmd := exec.Command("git", "cat-file", "--batch")
+	cmd.Dir = repo
+	cmd.Stdin = strings.NewReader(strings.Join(blobs, "\n") + "\n")
+
+	out, err := cmd.StdoutPipe()
+
+	if err != nil {
+		return err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	r := bufio.NewReader(out)
+
+	for range blobs {
+		// Looks like `<hash> <type> <size>` followed by contents and a newline.
+		header, err := r.ReadString('\n')
+
+		if err != nil {
+			cmd.Wait()
+
+			return err
+		}
+
+		w := strings.Fields(header)
+
+		if len(w) != 3 {
+			continue
+		}
+
+		size, err := strconv.ParseInt(w[2], 10, 64)
+
+		if err != nil {
+			cmd.Wait()
+
+			return err
+		}
+
+		blob, err := sniffBlob(io.LimitReader(r, size))
+
+		if err != nil {
+			cmd.Wait()
+
+			return err
+		}
+
+		// Skip the newline closing the contents.
+		if _, err := r.Discard(1); err != nil {
+			cmd.Wait()
+
+			return err
+		}
+
+		blob.binary = blob.binary || w[1] != "blob"
+		seen[w[0]] = blob
+	}
+
+	return cmd.Wait()
+}
+
+// Helps go through blob contents in chunks, counting lines as it goes.
+func sniffBlob(r io.Reader) (sniff, error) {
+	var blob sniff
+	var last byte
+
+	buf := make([]byte, 32<<10)
+
+	for {
+		n, err := r.Read(buf)
+		chunk := buf[:n]
+
+		if blob.size < sniffLimit {
+			end := sniffLimit - int(blob.size)
+
+			if end > n {
+				end = n
+			}
+
+			blob.binary = blob.binary || bytes.IndexByte(chunk[:end], 0) != -1
+		}
+
+		// Shebangs are short enough to turn up in the first chunk.
+		if blob.size == 0 {
+			line, _, _ := bytes.Cut(chunk, []byte("\n"))
+			blob.line = string(line)
+		}
+
+		if n > 0 {
+			blob.lines += bytes.Count(chunk, []byte("\n"))
+			last = chunk[n-1]
+		}
+
+		blob.size += int64(n)
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return blob, err
+		}
+	}
+
+	// Count a last line lacking a newline too.
+	if blob.size > 0 && last != '\n' {
+		blob.lines++
+	}
+
+	return blob, nil
+}
+
+// Helps normalize notes refs given in short form, `commits` say.
+func notesRef(ref string) string {
+	if strings.HasPrefix(ref, "refs/") {
+		return ref
+	}
+
+	return fmt.Sprintf("refs/notes/%s", ref)
+}
+
+// Collects notes per commit across the given notes refs, logging and
+// skipping any that fail to read.
+func notesParser(repo string, refs []string) map[string][]note {
+	results := make(map[string][]note)
+
+	for _, ref := range refs {
+		ref = notesRef(ref)
+
+		notes, err := notesRefParser(repo, ref)
+
+		if err != nil {
+			log.Printf("unable to read notes: %v", err)
+			continue
+		}
+
+		for c, n := range notes {
+			results[c] = append(results[c], n)
+		}
+	}
+
+	return results
+}
+
+// Collects notes per commit for a single notes ref.
+func notesRefParser(repo string, ref string) (map[string]note, error) {
+	results := make(map[string]note)
+
+	cmd := exec.Command("git", "notes", fmt.Sprintf("--ref=%s", ref), "list")
+	cmd.Dir = repo
+
+	out, err := cmd.Output()
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %v", ref, err)
+	}
+
+	var blobs, commits []string
+
+	// Looks like `<note blob> <annotated commit>`.
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		w := strings.Fields(line)
+
+		if len(w) != 2 {
+			continue
+		}
+
+		blobs = append(blobs, w[0])
+		commits = append(commits, w[1])
+	}
+
+	if len(blobs) == 0 {
+		return results, nil
+	}
+
+	cmd = exec.Command("git", "cat-file", "--batch")
+	cmd.Dir = repo
+	cmd.Stdin = strings.NewReader(strings.Join(blobs, "\n") + "\n")
+
+	out, err = cmd.Output()
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", ref, err)
+	}
+
+	r := bufio.NewReader(bytes.NewReader(out))
+
+	for _, c := range commits {
+		// Looks like `<hash> blob <size>` followed by contents and a newline.
+		header, err := r.ReadString('\n')
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", ref, err)
+		}
+
+		w := strings.Fields(header)
+
+		if len(w) != 3 {
+			continue
+		}
+
+		size, err := strconv.Atoi(w[2])
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", ref, err)
+		}
+
+		body := make([]byte, size+1)
+
+		if _, err := io.ReadFull(r, body); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", ref, err)
+		}
+
+		results[c] = note{
+			Body: strings.TrimSuffix(string(body[:size]), "\n"),
+			Ref:  strings.TrimPrefix(ref, "refs/notes/"),
+		}
+	}
+
+	return results, nil
+}
+
+// Splits up unit separated `Key: value` trailers.
+func trailerParser(s string) []trailer {
+	var results []trailer
+
+	for _, line := range strings.Split(s, "\x1f") {
+		k, v, ok := strings.Cut(line, ":")
+
+		if !ok {
+			continue
+		}
+
+		results = append(results, trailer{Key: strings.TrimSpace(k), Value: strings.TrimSpace(v)})
+	}
+
+	return results
+}
+
 func bodyParser(h string, repo string) (string, error) {
 	// Because the commit message body is multiline and is tripping the scanner.
 	cmd := exec.Command("git", "show", "--no-patch", "--format=%B", h)
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Helps run git in `dir` for setting up fixture repos, failing the test on error.
+func gitrun(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=Jo Doe",
+		"GIT_AUTHOR_EMAIL=jo@example.org",
+		"GIT_AUTHOR_DATE=2006-01-02T15:04:05Z",
+		"GIT_COMMITTER_NAME=Jo Doe",
+		"GIT_COMMITTER_EMAIL=jo@example.org",
+		"GIT_COMMITTER_DATE=2006-01-02T15:04:05Z",
+		"GIT_CONFIG_NOSYSTEM=1",
+		"HOME="+dir,
+	)
+
+	out, err := cmd.CombinedOutput()
+
+	if err != nil {
+		t.Fatalf("git %s: %v: %s", strings.Join(args, " "), err, out)
+	}
+
+	return strings.TrimSpace(string(out))
+}
+
+// Helps commit changes to a fixture repo, nil contents deleting files.
+func gitcommit(t *testing.T, dir string, files map[string][]byte, message string) string {
+	t.Helper()
+
+	for name, bs := range files {
+		p := filepath.Join(dir, name)
+
+		if bs == nil {
+			gitrun(t, dir, "rm", "-q", "--", name)
+
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(p, bs, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		gitrun(t, dir, "add", "--", name)
+	}
+
+	gitrun(t, dir, "commit", "-q", "--allow-empty", "-m", message)
+
+	return gitrun(t, dir, "rev-parse", "HEAD")
+}
+
+// Helps set up an empty fixture repo on a `main` branch.
+func gitinit(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	gitrun(t, dir, "init", "-q", "-b", "main")
+
+	return dir
+}
+
+func TestFileStatParser(t *testing.T) {
+	repo := gitinit(t)
+
+	var lines strings.Builder
+
+	for i := 1; i <= 10; i++ {
+		lines.WriteString(strings.Repeat("line ", 4) + string(rune('a'+i)) + "\n")
+	}
+
+	original := lines.String()
+
+	parent := gitcommit(t, repo, map[string][]byte{
+		"a.txt":                []byte("alpha\nbeta\ngamma\n"),
+		"c.txt":                []byte(original),
+		"dir with space/b.txt": []byte("one\n"),
+		"img.bin":              {0, 1, 2},
+		"new\nline.txt":        []byte("x\n"),
+		"old.txt":              []byte("gone\n"),
+	}, "First")
+
+	gitrun(t, repo, "mv", "a.txt", "renamed a.txt")
+
+	h := gitcommit(t, repo, map[string][]byte{
+		"added.txt":            []byte("fresh\n"),
+		"c.txt":                []byte(strings.Replace(original, "line line line line k", "changed", 1)),
+		"c2.txt":               []byte(original),
+		"dir with space/b.txt": []byte("one\ntwo\n"),
+		"img.bin":              {0, 3},
+		"new\nline.txt":        []byte("x\ny\n"),
+		"old.txt":              nil,
+	}, "Second")
+
+	files, err := fileStatParser(h, parent, repo)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]stat{
+		"added.txt":            {Adds: 1, NewSize: 6, OldPath: "added.txt", Path: "added.txt", Status: "A"},
+		"c.txt":                {Adds: 1, Dels: 1, NewSize: 206, OldPath: "c.txt", OldSize: 220, Path: "c.txt", Status: "M"},
+		"c2.txt":               {NewSize: 220, OldPath: "c.txt", OldSize: 220, Path: "c2.txt", Similarity: 100, Status: "C"},
+		"dir with space/b.txt": {Adds: 1, NewSize: 8, OldPath: "dir with space/b.txt", OldSize: 4, Path: "dir with space/b.txt", Status: "M"},
+		"img.bin":              {Bin: true, NewSize: 2, OldPath: "img.bin", OldSize: 3, Path: "img.bin", Status: "M"},
+		"new\nline.txt":        {Adds: 1, NewSize: 4, OldPath: "new\nline.txt", OldSize: 2, Path: "new\nline.txt", Status: "M"},
+		"old.txt":              {Dels: 1, OldPath: "old.txt", OldSize: 5, Path: "old.txt", Status: "D"},
+		"renamed a.txt":        {NewSize: 17, OldPath: "a.txt", OldSize: 17, Path: "renamed a.txt", Similarity: 100, Status: "R"},
+	}
+
+	if len(files) != len(want) {
+		t.Errorf("got %d files, want %d: %+v", len(files), len(want), files)
+	}
+
+	for _, f := range files {
+		if w, ok := want[f.Path]; !ok || f != w {
+			t.Errorf("got %+v, want %+v", f, w)
+		}
+	}
+
+	if got, want := summarize(files), (summary{Adds: 4, Bins: 1, Dels: 2, Files: 8}); got != want {
+		t.Errorf("got totals %+v, want %+v", got, want)
+	}
+}
+
+func TestBlobSizeParser(t *testing.T) {
+	repo := gitinit(t)
+
+	gitcommit(t, repo, map[string][]byte{"a.txt": []byte("hello\n")}, "First")
+
+	blob := gitrun(t, repo, "rev-parse", "HEAD:a.txt")
+	missing := strings.Repeat("1", 40)
+	zero := strings.Repeat("0", 40)
+
+	sizes, err := blobSizeParser([]string{blob, blob, missing, zero}, repo)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sizes) != 1 || sizes[blob] != 6 {
+		t.Errorf("got %v, want only %s sized 6", sizes, blob)
+	}
+
+	if sizes, err := blobSizeParser([]string{zero}, repo); err != nil || len(sizes) != 0 {
+		t.Errorf("got %v, %v for null blobs only", sizes, err)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		files []stat
+		want  summary
+	}{
+		{nil, summary{}},
+		{[]stat{{Adds: 3, Dels: 1}, {Bin: true}, {Adds: 2, Dels: 5}}, summary{Adds: 5, Bins: 1, Dels: 6, Files: 3}},
+	}
+
+	for _, tt := range tests {
+		if got := summarize(tt.files); got != tt.want {
+			t.Errorf("got %+v, want %+v", got, tt.want)
+		}
+	}
+}
+
+func TestNotesParser(t *testing.T) {
+	repo := gitinit(t)
+	hash := gitcommit(t, repo, nil, "First")
+
+	gitrun(t, repo, "notes", "--ref=review", "add", "-m", "Looks good", hash)
+	gitrun(t, repo, "notes", "add", "-m", "See also", hash)
+
+	gitcommit(t, repo, map[string][]byte{"a.txt": []byte("a\n")}, "Second")
+
+	// A notes ref pointing at a blob rather than a tree fails to list.
+	gitrun(t, repo, "update-ref", "refs/notes/broken", gitrun(t, repo, "rev-parse", "HEAD:a.txt"))
+
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	notes := notesParser(repo, []string{"review", "broken", "commits"})
+	got := notes[hash]
+
+	if len(got) != 2 {
+		t.Fatalf("got %d notes, want 2 with the broken ref skipped", len(got))
+	}
+
+	if got[0].Ref != "review" || got[0].Body != "Looks good" {
+		t.Errorf("got %+v, want review note first", got[0])
+	}
+
+	if got[1].Ref != "commits" || got[1].Body != "See also" {
+		t.Errorf("got %+v, want default notes after the broken ref", got[1])
+	}
+}
+
+func TestCombinedPathParser(t *testing.T) {
+	repo := gitinit(t)
+
+	gitcommit(t, repo, map[string][]byte{"f": []byte("base\n"), "g": []byte("base\n")}, "Base")
+	gitrun(t, repo, "checkout", "-q", "-b", "side")
+	side := gitcommit(t, repo, map[string][]byte{"f": []byte("side\n"), "g": []byte("side\n")}, "Side")
+	gitrun(t, repo, "checkout", "-q", "main")
+	main := gitcommit(t, repo, map[string][]byte{"g": []byte("main\n")}, "Main")
+
+	// Take one file from the side branch as is and resolve the other by hand.
+	resolved := gitcommit(t, repo, map[string][]byte{"f": []byte("side\n"), "g": []byte("both\n")}, "Resolved")
+	merge := gitrun(t, repo, "commit-tree", resolved+"^{tree}", "-p", main, "-p", side, "-m", "Merge")
+
+	got, err := combinedPathParser(merge, repo)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != 1 || got[0] != "g" {
+		t.Errorf("got %v, want g only", got)
+	}
+}
+
+func TestLanguageParser(t *testing.T) {
+	repo := gitinit(t)
+
+	gitcommit(t, repo, map[string][]byte{
+		"main.go":  []byte("package main\n"),
+		"run":      []byte("#!/bin/sh\necho hi\n"),
+		"logo":     {0x89, 'P', 'N', 'G', 0, 0},
+		"fake.go":  {'p', 0, 'k', '\n'},
+		"big":      []byte("\x00" + strings.Repeat("#\n", 64<<10)),
+		"COPYING":  []byte("Free\nsoftware"),
+		"sub/a.go": []byte("package sub\n\nfunc A() {}\n"),
+	}, "First")
+
+	tree, err := treeParser("HEAD", repo)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seen := make(sniffs)
+	b, err := languageParser(tree, repo, dialects(nil), seen)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]language)
+
+	for _, l := range b {
+		got[l.Name] = l
+	}
+
+	// Binaries are left out whatever their name or size.
+	if l := got["Go"]; l.Files != 2 || l.Lines != 4 || l.Bytes != 38 {
+		t.Errorf("got %+v, want 2 Go files, 4 lines in 38 bytes", l)
+	}
+
+	if l := got["Shell"]; l.Files != 1 || l.Lines != 2 || l.Bytes != 18 {
+		t.Errorf("got %+v, want the script only, sniffed for a shebang", l)
+	}
+
+	if l := got["Other"]; l.Files != 1 || l.Lines != 2 || l.Bytes != 13 {
+		t.Errorf("got %+v, want the license only", l)
+	}
+
+	if len(b) != 3 || b.Lines() != 8 {
+		t.Errorf("got %+v, want 3 languages", b)
+	}
+
+	// Blobs read once are not read again.
+	if len(seen) != 7 {
+		t.Errorf("got %d blobs sniffed, want 7", len(seen))
+	}
+
+	seen[tree[0].Hash] = sniff{lines: 100, size: 1}
+
+	if again, err := languageParser(tree, repo, dialects(nil), seen); err != nil || again.Lines() == b.Lines() {
+		t.Errorf("got %+v, %v, want cached figures used", again, err)
+	}
+}
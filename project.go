@@ -10,6 +10,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 )
 
@@ -20,33 +21,77 @@ const SEP = "6f6c1745-e902-474a-9e99-08d0084fb011"
 var types = make(map[string]bool)
 
 type project struct {
-	base     string
-	Name     string
-	repo     string
-	options  *options
-	template *template.Template
+	base string
+	// Earlier clones to borrow objects from when archiving many projects.
+	clones []string
+	// Sorted list of commit hashes in the archive for linking to.
+	hashes    []string
+	links     []linkrule
+	Name      string
+	repo      string
+	options   *options
+	templates templates
 }
 
-func NewProject(base string, repo string, options *options) *project {
-	funcMap := template.FuncMap{
+func NewProject(base string, repo string, options *options, t templates) *project {
+	links, err := linkrules(options.LinkRules)
+
+	if err != nil {
+		log.Printf("unable to parse link rules: %v", err)
+	}
+
+	p := &project{
+		base:    base,
+		links:   links,
+		Name:    options.Name,
+		repo:    repo,
+		options: options,
+	}
+
+	p.templates = t.clone(template.FuncMap{
+		"autolink": func(s string) template.HTML {
+			return autolink(s, p.links, p.hashes)
+		},
+	})
+
+	return p
+}
+
+// Lists functions available to templates. Templates are parsed once for
+// sharing across projects, each project swapping in its own autolink function
+// and builds swapping in theme assets, extra pages, and locale once known.
+func funcMap() template.FuncMap {
+	results := template.FuncMap{
+		"autolink": func(s string) template.HTML {
+			return template.HTML(template.HTMLEscapeString(s))
+		},
+		"asset": asset,
+		"pages": func() []extra {
+			return nil
+		},
 		"diffstatbodyparser": diffstatbodyparser,
 		"diffbodyparser":     diffbodyparser,
+		"diffsplitparser":    diffsplitparser,
 	}
 
-	t := template.Must(template.New("page").Funcs(funcMap).Parse(tpl))
+	for k, v := range helpers {
+		results[k] = v
+	}
 
-	return &project{
-		base:     base,
-		Name:     options.Name,
-		repo:     repo,
-		options:  options,
-		template: t,
+	// Builds set up their own locale, English standing in until then.
+	if l, err := loadLocale("", defaultLocale, ""); err == nil {
+		for k, v := range l.funcs() {
+			results[k] = v
+		}
 	}
+
+	return results
 }
 
-// Creates base directories for holding objects, branches, and commits.
+// Creates base directories for holding objects, branches, commits, authors,
+// and stats.
 func (p *project) init() error {
-	dirs := []string{"branch", "commit", "object"}
+	dirs := []string{"author", "branch", "commit", "object", "stats"}
 
 	for _, dir := range dirs {
 		d := filepath.Join(p.base, dir)
@@ -72,7 +117,31 @@ func (p *project) save() error {
 		return err
 	}
 
-	return exec.Command("git", "clone", p.options.Source, p.repo).Run()
+	args := []string{"clone"}
+
+	for _, c := range p.clones {
+		args = append(args, "--reference-if-able", c)
+	}
+
+	args = append(args, p.options.Source, p.repo)
+
+	return exec.Command("git", args...).Run()
+}
+
+// Fetches notes refs, which are left out when cloning.
+func (p *project) fetchNotes() {
+	for _, ref := range p.options.Notes {
+		ref = notesRef(ref)
+
+		cmd := exec.Command("git", "fetch", "--force", "origin", fmt.Sprintf("%s:%s", ref, ref))
+		cmd.Dir = p.repo
+
+		log.Printf("fetching notes: %s", ref)
+
+		if _, err := cmd.Output(); err != nil {
+			log.Printf("unable to fetch notes: %v", err)
+		}
+	}
 }
 
 func (p *project) updateBranches(branches []branch) {
@@ -93,6 +162,16 @@ func (p *project) updateBranches(branches []branch) {
 }
 
 func (p *project) writePages(branches []branch) {
+	// Commits can only link to others known ahead of time.
+	for _, b := range branches {
+		for _, c := range b.Commits {
+			p.hashes = append(p.hashes, c.Hash)
+		}
+	}
+
+	p.hashes = dedupe(p.hashes)
+	sort.Strings(p.hashes)
+
 	for _, b := range branches {
 		log.Printf("processing branch: %s", b)
 
@@ -115,6 +194,11 @@ func (p *project) writePages(branches []branch) {
 				p.writeCommitDiff(base, b, c, par)
 			}
 
+			// Merges get a combined diff showing only what was resolved on top.
+			if len(c.Parents) > 1 {
+				p.writeCombinedDiff(base, b, c)
+			}
+
 			for _, obj := range c.Tree {
 				dst := filepath.Join(p.base, "object", obj.Dir())
 
@@ -135,7 +219,7 @@ func (p *project) writePages(branches []branch) {
 	}
 }
 
-func (p *project) writeMainIndex(branches []branch) {
+func (p *project) writeMainIndex(branches []branch, tags []tag, meta metadata) {
 	// This is the main index or project home.
 	f, err := os.Create(filepath.Join(p.base, "index.html"))
 
@@ -147,21 +231,127 @@ func (p *project) writeMainIndex(branches []branch) {
 
 	page := page{
 		Base: "./",
-		Data: Data{
-			"Branches": branches,
-			"Source":   p.options.Source,
-			"Project":  p.Name,
+		Data: indexView{
+			About:    meta,
+			Authors:  contributors(branches),
+			Branches: branches,
+			Source:   p.options.Source,
+			Project:  p.Name,
+			Tags:     tags,
 		},
 		Title: p.Name,
 	}
 
-	if err := p.template.Execute(f, page); err != nil {
+	if err := p.templates.render(f, kindIndex, page); err != nil {
 		log.Fatalf("unable to apply template: %v", err)
 	}
 }
 
+// Writes out a JSON summary of the project next to its home page.
+func (p *project) writeExport(branches []branch, tags []tag, meta metadata) {
+	e := newExport(p.Name, p.options.Source, branches, tags, meta)
+
+	if err := writeExport(filepath.Join(p.base, exportName), e); err != nil {
+		log.Printf("unable to export project: %v", err)
+	}
+}
+
+func (p *project) writeAuthorPages(branches []branch) {
+	for _, a := range contributors(branches) {
+		dst := filepath.Join(p.base, "author", a.Slug, "index.html")
+
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			log.Printf("unable to create author directory: %v", err)
+
+			continue
+		}
+
+		f, err := os.Create(dst)
+
+		if err != nil {
+			log.Printf("unable to create author page: %v", err)
+
+			continue
+		}
+
+		page := page{
+			Base: "../../",
+			Data: authorView{
+				Author:  a,
+				Project: p.Name,
+			},
+			Title: strings.Join([]string{p.Name, a.Name}, ": "),
+		}
+
+		if err := p.templates.render(f, kindAuthor, page); err != nil {
+			log.Printf("unable to apply template: %v", err)
+		}
+
+		f.Close()
+	}
+}
+
+func (p *project) writeStatsPage(branches []branch) {
+	dir := filepath.Join(p.base, "stats")
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Printf("unable to create stats directory: %v", err)
+
+		return
+	}
+
+	periods, err := days(p.options.HotspotWindows)
+
+	if err != nil {
+		log.Printf("unable to parse hotspot windows: %v", err)
+
+		periods = []int{0}
+	}
+
+	// Maps file names to page kinds.
+	kinds := map[string]string{
+		"hotspots.html": kindHotspots,
+		"index.html":    kindStats,
+	}
+
+	pages := map[string]page{
+		"index.html": {
+			Base: "../",
+			Data: statsView{
+				Project: p.Name,
+				Stats:   statistics(branches),
+			},
+			Title: strings.Join([]string{p.Name, "Stats"}, ": "),
+		},
+		"hotspots.html": {
+			Base: "../",
+			Data: hotspotsView{
+				Hotspots: windows(branches, periods),
+				Project:  p.Name,
+			},
+			Title: strings.Join([]string{p.Name, "Hotspots"}, ": "),
+		},
+	}
+
+	for name, page := range pages {
+		f, err := os.Create(filepath.Join(dir, name))
+
+		if err != nil {
+			log.Printf("unable to create stats page: %v", err)
+
+			continue
+		}
+
+		if err := p.templates.render(f, kinds[name], page); err != nil {
+			log.Printf("unable to apply template: %v", err)
+		}
+
+		f.Close()
+	}
+}
+
 func (p *project) writeCommitDiff(base string, b branch, c commit, par string) {
-	cmd := exec.Command("git", "diff", "-p", fmt.Sprintf("%s..%s", par, c.Hash))
+	cmd := exec.Command("git", "diff", "-p", "-M", "-C", fmt.Sprintf("%s..%s", par, c.Hash))
 	cmd.Dir = p.repo
 
 	out, err := cmd.Output()
@@ -172,39 +362,114 @@ func (p *project) writeCommitDiff(base string, b branch, c commit, par string) {
 		return
 	}
 
-	dst := filepath.Join(base, fmt.Sprintf("diff-%s.html", par))
-	f, err := os.Create(dst)
+	p.writeDiff(base, b, diff{Commit: c, Parent: par}, out)
+}
 
-	defer f.Close()
+func (p *project) writeCombinedDiff(base string, b branch, c commit) {
+	cmd := exec.Command("git", "diff-tree", "-p", "--cc", "-M", "-C", "--no-commit-id", c.Hash)
+	cmd.Dir = p.repo
+
+	out, err := cmd.Output()
 
 	if err != nil {
-		log.Printf("unable to create commit diff to parent: %v", err)
+		log.Printf("unable to diff against parents: %v", err)
 
 		return
 	}
 
-	page := page{
-		Base: "../../",
-		Data: Data{
-			"Diff": diff{
-				Body:   fmt.Sprintf("%s", out),
-				Commit: c,
-				Parent: par,
-			},
-			"Path": Data{
-				"Branch": b.Name,
-				"Commit": par,
-			},
-			"Project": p.Name,
-		},
-		Title: strings.Join([]string{p.Name, b.Name, c.Abbr}, ": "),
+	p.writeDiff(base, b, diff{Combined: true, Commit: c}, out)
+}
+
+// Writes out diff pages, splitting into per file pages or collapsing file diffs as needed.
+func (p *project) writeDiff(base string, b branch, d diff, out []byte) {
+	d.Collapsed = make(map[string]int)
+
+	// Keep a raw copy around for linking to from collapsed or oversized diffs.
+	if err := os.WriteFile(filepath.Join(base, d.Patch()), out, 0644); err != nil {
+		log.Printf("unable to write raw patch: %v", err)
 	}
 
-	if err := p.template.Execute(f, page); err != nil {
-		log.Printf("unable to apply template: %v", err)
+	files := patchsplitter(fmt.Sprintf("%s", out))
+
+	var body strings.Builder
+	var total int
+
+	for i, f := range files {
+		total += f.Lines
+
+		if p.options.CollapseLines > 0 && f.Lines > p.options.CollapseLines {
+			d.Collapsed[f.Path] = f.Lines
+			files[i].Body = patchhead(f.Body)
+		}
+
+		body.WriteString(files[i].Body)
+	}
+
+	if p.options.SplitLines <= 0 || total <= p.options.SplitLines {
+		d.Body = body.String()
+		p.writeDiffPage(base, b, d)
 
 		return
 	}
+
+	// Oversized diffs turn into an index of per file pages.
+	d.Files = files
+	p.writeDiffPage(base, b, d)
+
+	for _, f := range files {
+		fd := d
+
+		fd.Body = f.Body
+		fd.Part = f.Index
+
+		p.writeDiffPage(base, b, fd)
+	}
+}
+
+func (p *project) writeDiffPage(base string, b branch, d diff) {
+	variants := []bool{false, true}
+
+	// Index and combined diff pages have nothing to show side by side.
+	if d.Combined || (d.Files != nil && d.Part == 0) {
+		variants = variants[:1]
+	}
+
+	title := []string{p.Name, b.Name, d.Commit.Abbr}
+
+	if d.Part > 0 {
+		title = append(title, d.Files[d.Part-1].Path)
+	}
+
+	for _, split := range variants {
+		d.Split = split
+
+		f, err := os.Create(filepath.Join(base, d.Name(split)))
+
+		if err != nil {
+			log.Printf("unable to create commit diff to parent: %v", err)
+
+			return
+		}
+
+		page := page{
+			Base: "../../",
+			Data: diffView{
+				Diff: d,
+				Path: trail{
+					Branch: b.Name,
+					Commit: d.Parent,
+				},
+				Project: p.Name,
+			},
+			Title: strings.Join(title, ": "),
+		}
+
+		if err := p.templates.render(f, kindDiff, page); err != nil {
+			log.Printf("unable to apply template: %v", err)
+		}
+
+		f.Close()
+	}
 }
 
 func (p *project) writeBranchPage(b branch) {
@@ -231,15 +496,14 @@ func (p *project) writeBranchPage(b branch) {
 
 	page := page{
 		Base: "../../",
-		Data: Data{
-			"Commits": b.Commits,
-			"Branch":  b,
-			"Project": p.Name,
+		Data: branchView{
+			Branch:  b,
+			Project: p.Name,
 		},
 		Title: strings.Join([]string{p.Name, b.Name}, ": "),
 	}
 
-	if err := p.template.Execute(f, page); err != nil {
+	if err := p.templates.render(f, kindBranch, page); err != nil {
 		log.Printf("unable to apply template: %v", err)
 
 		return
@@ -339,18 +603,18 @@ func (p *project) writeObject(obj object, dst string, base string, b branch, c c
 
 	page := page{
 		Base: "../../",
-		Data: Data{
-			"Object": *o,
-			"Path": Data{
-				"Branch": b.Name,
-				"Commit": c.Hash,
+		Data: objectView{
+			Object: *o,
+			Path: trail{
+				Branch: b.Name,
+				Commit: c.Hash,
 			},
-			"Project": p.Name,
+			Project: p.Name,
 		},
 		Title: strings.Join([]string{p.Name, b.Name, c.Abbr, obj.Path}, ": "),
 	}
 
-	if err := p.template.Execute(f, page); err != nil {
+	if err := p.templates.render(f, kindObject, page); err != nil {
 		log.Printf("unable to apply template: %v", err)
 
 		return
@@ -364,6 +628,27 @@ func (p *project) writeObject(obj object, dst string, base string, b branch, c c
 		return
 	}
 
+	// Pages nested deeper than the object store need a base of their own.
+	if depth := strings.Count(obj.Path, "/"); depth > 0 {
+		page.Base = strings.Repeat("../", depth+2)
+
+		l, err := os.Create(lnk)
+
+		if err != nil {
+			log.Printf("unable to create nested object page: %v", err)
+
+			return
+		}
+
+		defer l.Close()
+
+		if err := p.templates.render(l, kindObject, page); err != nil {
+			log.Printf("unable to apply template: %v", err)
+		}
+
+		return
+	}
+
 	if err := os.Link(dst, lnk); err != nil {
 		if os.IsExist(err) {
 			return
@@ -395,17 +680,17 @@ func (p *project) writeCommitPage(base string, b branch, c commit) {
 
 	page := page{
 		Base: "../../",
-		Data: Data{
-			"Commit": c,
-			"Path": Data{
-				"Branch": b.Name,
+		Data: commitView{
+			Commit: c,
+			Path: trail{
+				Branch: b.Name,
 			},
-			"Project": p.Name,
+			Project: p.Name,
 		},
 		Title: strings.Join([]string{p.Name, b.Name, c.Abbr}, ": "),
 	}
 
-	if err := p.template.Execute(f, page); err != nil {
+	if err := p.templates.render(f, kindCommit, page); err != nil {
 		log.Printf("unable to apply template: %v", err)
 	}
 }
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Describes one of many projects archived side by side, as listed in the config file.
+type repository struct {
+	Branches    manyflag `json:"branches,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Homepage    string   `json:"homepage,omitempty"`
+	Name        string   `json:"name"`
+	Source      string   `json:"source"`
+	Topics      manyflag `json:"topics,omitempty"`
+}
+
+// Helps derive per project options, settings other than those given
+// per repository being shared.
+func (r repository) options(shared *options) *options {
+	o := *shared
+
+	// Descriptive settings only make sense per project.
+	o.Description = r.Description
+	o.Homepage = r.Homepage
+	o.Projects = nil
+	o.Source = r.Source
+	o.Topics = r.Topics
+
+	if r.Name != "" {
+		o.Name = r.Name
+	}
+
+	if len(r.Branches) > 0 {
+		o.Branches = r.Branches
+	}
+
+	return &o
+}
+
+// Helps name subdirectories for projects archived side by side after each
+// project's name. Names that slugify alike, or clash with what builds write
+// at the top level, are numbered in order of appearance.
+func projectSlugs(projects []repository, opt *options) []string {
+	var results []string
+
+	taken := make(map[string]bool)
+
+	for _, name := range append(append([]string{}, generated...), shared...) {
+		taken[name] = true
+	}
+
+	for _, r := range projects {
+		base := slugify(r.options(opt).Name)
+		slug := base
+
+		for i := 2; taken[slug]; i++ {
+			slug = fmt.Sprintf("%s-%d", base, i)
+		}
+
+		taken[slug] = true
+		results = append(results, slug)
+	}
+
+	return results
+}
+
+// Lists a project on the site index.
+type listing struct {
+	metadata
+	Name    string
+	Path    string
+	Source  string
+	Updated time.Time
+}
+
+// Helps find the most recent commit date across branches.
+func lastUpdated(branches []branch) time.Time {
+	var last time.Time
+
+	for _, b := range branches {
+		for _, c := range b.Commits {
+			if c.Committed.After(last) {
+				last = c.Committed
+			}
+		}
+	}
+
+	return last
+}
+
+// Writes the top level index listing every project, most recently updated first.
+func writeSiteIndex(base string, t templates, name string, listings []listing) error {
+	sort.SliceStable(listings, func(i, j int) bool {
+		return listings[i].Updated.After(listings[j].Updated)
+	})
+
+	f, err := os.Create(filepath.Join(base, "index.html"))
+
+	if err != nil {
+		return fmt.Errorf("unable to create site index: %v", err)
+	}
+
+	defer f.Close()
+
+	page := page{
+		Base: "./",
+		Data: siteView{
+			Project:  name,
+			Projects: listings,
+		},
+		Title: name,
+	}
+
+	if err := t.render(f, kindSite, page); err != nil {
+		return fmt.Errorf("unable to apply template: %v", err)
+	}
+
+	return nil
+}
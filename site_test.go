@@ -0,0 +1,83 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRepositoryOptions(t *testing.T) {
+	shared := defaults()
+	shared.Branches = manyflag{"main"}
+	shared.CollapseLines = 10
+	shared.Projects = []repository{{Name: "One"}}
+
+	r := repository{Name: "Two", Source: "/src/two", Branches: manyflag{"develop"}}
+	o := r.options(shared)
+
+	if o.Name != "Two" || o.Source != "/src/two" {
+		t.Errorf("got %s from %s, want Two from /src/two", o.Name, o.Source)
+	}
+
+	if len(o.Branches) != 1 || o.Branches[0] != "develop" {
+		t.Errorf("got branches %v, want develop", o.Branches)
+	}
+
+	if o.CollapseLines != 10 {
+		t.Errorf("got collapse-lines %d, want shared setting", o.CollapseLines)
+	}
+
+	if o.Projects != nil {
+		t.Error("projects carried over")
+	}
+
+	if shared.Name != "Jimbo" || shared.Branches[0] != "main" {
+		t.Error("shared options modified")
+	}
+
+	if o := (repository{Source: "/src/three"}).options(shared); o.Name != "Jimbo" || o.Branches[0] != "main" {
+		t.Errorf("got %s on %v, want shared name and branches", o.Name, o.Branches)
+	}
+}
+
+func TestLastUpdated(t *testing.T) {
+	day := time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+	branches := []branch{
+		{Commits: []commit{{Committed: day}, {Committed: day.AddDate(0, 0, -3)}}},
+		{Commits: []commit{{Committed: day.AddDate(0, 0, 2)}}},
+	}
+
+	if got := lastUpdated(branches); !got.Equal(day.AddDate(0, 0, 2)) {
+		t.Errorf("got %v, want %v", got, day.AddDate(0, 0, 2))
+	}
+
+	if got := lastUpdated(nil); !got.IsZero() {
+		t.Errorf("got %v, want zero time", got)
+	}
+}
+
+func TestProjectSlugs(t *testing.T) {
+	shared := defaults()
+	projects := []repository{
+		{Name: "Go Tools"},
+		{Name: "go-tools"},
+		{Source: "/src/unnamed"},
+		{Name: "Go.Tools"},
+		{Name: "Assets"},
+		{Source: "/src/other"},
+	}
+
+	got := projectSlugs(projects, shared)
+	want := []string{"go-tools", "go-tools-2", "jimbo", "go-tools-3", "assets-2", "jimbo-2"}
+
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got %v, want %v", got, want)
+
+			break
+		}
+	}
+}
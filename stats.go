@@ -0,0 +1,387 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Caps the number of busiest files and top authors listed.
+const statsTop = 20
+
+// Holds per project activity figures for the stats page.
+type stats struct {
+	Authors []contributor
+	Commits int
+	Files   []hotfile
+	Monthly []bucket
+	// Commit counts by weekday, starting on Sunday, and hour of day.
+	Punch  [7][24]int
+	Weekly []bucket
+}
+
+// Adds up activity over a period of time.
+type bucket struct {
+	Adds    int
+	Commits int
+	Dels    int
+	Start   time.Time
+}
+
+// Tracks how often a file is touched.
+type hotfile struct {
+	Adds    int
+	Commits int
+	Dels    int
+	// Points to the most recent commit with the file in its tree, if any.
+	Latest string
+	Path   string
+}
+
+// Lists hotspots within a number of days of the latest commit, zero meaning all time.
+type window struct {
+	Days  int
+	Files []hotfile
+}
+
+// Churn returns the total number of lines changed.
+func (h hotfile) Churn() int {
+	return h.Adds + h.Dels
+}
+
+// Helps collect activity figures across branches, counting shared commits once.
+func statistics(branches []branch) stats {
+	var s stats
+
+	weekly := make(map[time.Time]*bucket)
+	monthly := make(map[time.Time]*bucket)
+	seen := make(map[string]bool)
+
+	for _, b := range branches {
+		for _, c := range b.Commits {
+			if seen[c.Hash] {
+				continue
+			}
+
+			seen[c.Hash] = true
+			s.Commits++
+
+			// Punch cards make the most sense in the author's local time.
+			s.Punch[c.Date.Weekday()][c.Date.Hour()]++
+
+			tally(weekly, weekof(c.Date), c)
+			tally(monthly, monthof(c.Date), c)
+		}
+	}
+
+	s.Weekly = fillbuckets(weekly, func(t time.Time) time.Time { return t.AddDate(0, 0, 7) })
+	s.Monthly = fillbuckets(monthly, func(t time.Time) time.Time { return t.AddDate(0, 1, 0) })
+
+	s.Files = touched(branches).hotspots(time.Time{})
+
+	s.Authors = contributors(branches)
+
+	if len(s.Authors) > statsTop {
+		s.Authors = s.Authors[:statsTop]
+	}
+
+	return s
+}
+
+// Holds unique commits across branches along with the most recent commit
+// each file was seen in, collected once for ranking over any number of windows.
+type touches struct {
+	commits []commit
+	latest  map[string]commit
+}
+
+// Helps gather commits and latest sightings per file in a single pass.
+func touched(branches []branch) touches {
+	t := touches{latest: make(map[string]commit)}
+	seen := make(map[string]bool)
+
+	for _, b := range branches {
+		for _, c := range b.Commits {
+			if seen[c.Hash] {
+				continue
+			}
+
+			seen[c.Hash] = true
+			t.commits = append(t.commits, c)
+
+			for _, obj := range c.Tree {
+				if l, ok := t.latest[obj.Path]; !ok || c.Date.After(l.Date) {
+					t.latest[obj.Path] = c
+				}
+			}
+		}
+	}
+
+	return t
+}
+
+// Helps rank files by number of commits touching them since a given date,
+// then by lines churned. Changes are taken against first parents only.
+func (t touches) hotspots(since time.Time) []hotfile {
+	var results []hotfile
+
+	files := make(map[string]*hotfile)
+
+	for _, c := range t.commits {
+		if c.Date.Before(since) || len(c.History) == 0 {
+			continue
+		}
+
+		for _, f := range c.History[0].Files {
+			h, ok := files[f.Path]
+
+			if !ok {
+				h = &hotfile{Path: f.Path, Latest: t.latest[f.Path].Hash}
+				files[f.Path] = h
+			}
+
+			h.Commits++
+			h.Adds += f.Adds
+			h.Dels += f.Dels
+		}
+	}
+
+	for _, h := range files {
+		results = append(results, *h)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Commits != results[j].Commits {
+			return results[i].Commits > results[j].Commits
+		}
+
+		if results[i].Churn() != results[j].Churn() {
+			return results[i].Churn() > results[j].Churn()
+		}
+
+		return results[i].Path < results[j].Path
+	})
+
+	if len(results) > statsTop {
+		results = results[:statsTop]
+	}
+
+	return results
+}
+
+// Helps collect hotspots over windows of days counting back from the latest commit.
+func windows(branches []branch, days []int) []window {
+	var last time.Time
+
+	t := touched(branches)
+
+	for _, c := range t.commits {
+		if c.Date.After(last) {
+			last = c.Date
+		}
+	}
+
+	var results []window
+
+	for _, d := range days {
+		var since time.Time
+
+		if d > 0 {
+			since = last.AddDate(0, 0, -d)
+		}
+
+		results = append(results, window{Days: d, Files: t.hotspots(since)})
+	}
+
+	return results
+}
+
+// Helps add up a commit's changes into the bucket starting at `start`.
+func tally(m map[time.Time]*bucket, start time.Time, c commit) {
+	b, ok := m[start]
+
+	if !ok {
+		b = &bucket{Start: start}
+		m[start] = b
+	}
+
+	b.Commits++
+	b.Adds += c.Totals.Adds
+	b.Dels += c.Totals.Dels
+}
+
+// Helps bucket dates by week starting on Monday, in UTC.
+func weekof(t time.Time) time.Time {
+	t = t.UTC()
+	d := (int(t.Weekday()) + 6) % 7
+
+	return time.Date(t.Year(), t.Month(), t.Day()-d, 0, 0, 0, 0, time.UTC)
+}
+
+// Helps bucket dates by month, in UTC.
+func monthof(t time.Time) time.Time {
+	t = t.UTC()
+
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// Helps sort buckets by date, filling in gaps with empty ones.
+func fillbuckets(m map[time.Time]*bucket, next func(time.Time) time.Time) []bucket {
+	var results []bucket
+
+	if len(m) == 0 {
+		return results
+	}
+
+	var first, last time.Time
+
+	for k := range m {
+		if first.IsZero() || k.Before(first) {
+			first = k
+		}
+
+		if k.After(last) {
+			last = k
+		}
+	}
+
+	for t := first; !t.After(last); t = next(t) {
+		if b, ok := m[t]; ok {
+			results = append(results, *b)
+		} else {
+			results = append(results, bucket{Start: t})
+		}
+	}
+
+	return results
+}
+
+// Helps draw commits per week.
+func (l *locale) weeklychart(s stats) template.HTML {
+	return l.barchart(s.Weekly, "iso")
+}
+
+// Helps draw commits per month.
+func (l *locale) monthlychart(s stats) template.HTML {
+	return l.barchart(s.Monthly, "Jan 2006")
+}
+
+// Helps draw additions above and deletions below the line per month.
+func (l *locale) churnchart(s stats) template.HTML {
+	const w, h = 12, 60
+
+	var max int
+
+	for _, b := range s.Monthly {
+		max = maxint(max, maxint(b.Adds, b.Dels))
+	}
+
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" width="%d" height="%d">`, len(s.Monthly)*w, 2*h, len(s.Monthly)*w, 2*h)
+
+	for i, b := range s.Monthly {
+		label := fmt.Sprintf("%s: +%d -%d", l.format("Jan 2006", b.Start), b.Adds, b.Dels)
+		a := scale(b.Adds, max, h)
+		d := scale(b.Dels, max, h)
+
+		fmt.Fprintf(&sb, `<g><title>%s</title>`, template.HTMLEscapeString(label))
+		fmt.Fprintf(&sb, `<rect x="%d" y="%d" width="%d" height="%d" fill="seagreen"/>`, i*w, h-a, w-2, a)
+		fmt.Fprintf(&sb, `<rect x="%d" y="%d" width="%d" height="%d" fill="crimson"/>`, i*w, h, w-2, d)
+		sb.WriteString("</g>")
+	}
+
+	sb.WriteString("</svg>")
+
+	return template.HTML(sb.String())
+}
+
+// Helps draw commits by weekday and hour as circles sized to match.
+func (l *locale) punchcard(s stats) template.HTML {
+	const cell, left, top = 20, 40, 20
+
+	var max int
+
+	for _, row := range s.Punch {
+		for _, n := range row {
+			max = maxint(max, n)
+		}
+	}
+
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" width="%d" height="%d" font-size="10" fill="currentColor">`, left+24*cell, top+7*cell, left+24*cell, top+7*cell)
+
+	for hour := 0; hour < 24; hour += 3 {
+		fmt.Fprintf(&sb, `<text x="%d" y="12" text-anchor="middle">%02d</text>`, left+hour*cell+cell/2, hour)
+	}
+
+	for day, row := range s.Punch {
+		// January 1st 2006 was a Sunday, standing in for naming days.
+		weekday := time.Date(2006, 1, 1+day, 0, 0, 0, 0, time.UTC)
+
+		fmt.Fprintf(&sb, `<text x="0" y="%d">%s</text>`, top+day*cell+cell/2+4, template.HTMLEscapeString(l.format("Mon", weekday)))
+
+		for hour, n := range row {
+			if n == 0 {
+				continue
+			}
+
+			// Area rather than radius follows the count.
+			r := math.Sqrt(float64(n)/float64(max)) * cell / 2
+
+			fmt.Fprintf(&sb, `<circle cx="%d" cy="%d" r="%.1f"><title>%s %02d:00: %d</title></circle>`, left+hour*cell+cell/2, top+day*cell+cell/2, r, template.HTMLEscapeString(l.format("Monday", weekday)), hour, n)
+		}
+	}
+
+	sb.WriteString("</svg>")
+
+	return template.HTML(sb.String())
+}
+
+// Helps draw commit counts as a bar chart with tooltips, dates formatted
+// using `layout`.
+func (l *locale) barchart(buckets []bucket, layout string) template.HTML {
+	const w, h = 12, 100
+
+	var max int
+
+	for _, b := range buckets {
+		max = maxint(max, b.Commits)
+	}
+
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" width="%d" height="%d" fill="currentColor">`, len(buckets)*w, h, len(buckets)*w, h)
+
+	for i, b := range buckets {
+		v := scale(b.Commits, max, h)
+		label := fmt.Sprintf("%s: %d", l.format(layout, b.Start), b.Commits)
+
+		fmt.Fprintf(&sb, `<rect x="%d" y="%d" width="%d" height="%d"><title>%s</title></rect>`, i*w, h-v, w-2, v, template.HTMLEscapeString(label))
+	}
+
+	sb.WriteString("</svg>")
+
+	return template.HTML(sb.String())
+}
+
+// Helps scale values to fit a given size, keeping non zero values visible.
+func scale(n, max, size int) int {
+	if n <= 0 || max <= 0 {
+		return 0
+	}
+
+	return maxint(1, n*size/max)
+}
+
+func maxint(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
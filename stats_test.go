@@ -0,0 +1,119 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStatistics(t *testing.T) {
+	mon := time.Date(2022, 1, 3, 9, 0, 0, 0, time.UTC)
+	jo := author{Name: "Jo"}
+
+	commits := []commit{
+		{Author: jo, Date: mon.AddDate(0, 1, 0), Hash: "c", Totals: summary{Adds: 5}},
+		{Author: jo, Date: mon.AddDate(0, 0, 1), Hash: "b", History: []overview{{Files: []stat{{Path: "a", Adds: 1}}}}},
+		{Author: jo, Date: mon, Hash: "a", History: []overview{{Files: []stat{{Path: "a", Dels: 2}, {Path: "b"}}}}},
+	}
+
+	s := statistics([]branch{{Commits: commits}, {Commits: commits[1:]}})
+
+	if s.Commits != 3 {
+		t.Errorf("expected 3 commits, got %d", s.Commits)
+	}
+
+	// Gaps between the first and last weeks are filled in.
+	if len(s.Weekly) != 5 || s.Weekly[0].Commits != 2 || s.Weekly[4].Commits != 1 {
+		t.Errorf("failed to bucket weeks: %+v", s.Weekly)
+	}
+
+	if len(s.Monthly) != 2 || s.Monthly[1].Adds != 5 {
+		t.Errorf("failed to bucket months: %+v", s.Monthly)
+	}
+
+	if s.Punch[time.Monday][9] != 1 || s.Punch[time.Tuesday][9] != 1 {
+		t.Errorf("failed to punch card: %v", s.Punch)
+	}
+
+	if len(s.Files) != 2 || s.Files[0].Path != "a" || s.Files[0].Churn() != 3 {
+		t.Errorf("failed to rank files: %+v", s.Files)
+	}
+
+	en, err := loadLocale("", "en", "")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, svg := range []string{string(en.weeklychart(s)), string(en.monthlychart(s)), string(en.churnchart(s)), string(en.punchcard(s))} {
+		if !strings.HasPrefix(svg, "<svg") || !strings.HasSuffix(svg, "</svg>") {
+			t.Errorf("failed to draw chart: %v", svg)
+		}
+	}
+
+	de, err := loadLocale("", "de", "")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Labels follow the locale.
+	if svg := string(de.punchcard(s)); !strings.Contains(svg, ">Mo.</text>") || !strings.Contains(svg, "<title>Montag 09:00: 1</title>") {
+		t.Errorf("got %v, want German day names", svg)
+	}
+
+	if svg := string(en.punchcard(s)); !strings.Contains(svg, ">Mon</text>") {
+		t.Errorf("got %v, want English day names", svg)
+	}
+}
+
+func TestHotspots(t *testing.T) {
+	now := time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	commits := []commit{
+		{Date: now, Hash: "c", History: []overview{{Files: []stat{{Path: "b", Adds: 9}}}}, Tree: []object{{Path: "b"}}},
+		{Date: now.AddDate(0, 0, -10), Hash: "b", History: []overview{{Files: []stat{{Path: "a", Adds: 1}}}}, Tree: []object{{Path: "a"}, {Path: "b"}}},
+		{Date: now.AddDate(0, 0, -100), Hash: "a", History: []overview{{Files: []stat{{Path: "a", Dels: 1}, {Path: "b"}}}}, Tree: []object{{Path: "a"}, {Path: "b"}}},
+	}
+
+	list := windows([]branch{{Commits: commits}}, []int{7, 30, 0})
+
+	if len(list[0].Files) != 1 || list[0].Files[0].Path != "b" {
+		t.Errorf("failed to rank last 7 days: %+v", list[0].Files)
+	}
+
+	// Ties on commits are broken by churn.
+	if f := list[1].Files; len(f) != 2 || f[0].Path != "b" || f[1].Path != "a" {
+		t.Errorf("failed to rank last 30 days: %+v", f)
+	}
+
+	// Deleted files link to the last commit having them around.
+	if f := list[2].Files; f[1].Path != "a" || f[1].Commits != 2 || f[1].Latest != "b" || f[0].Latest != "c" {
+		t.Errorf("failed to rank all time: %+v", f)
+	}
+}
+
+func TestInitClearsStats(t *testing.T) {
+	base := t.TempDir()
+	stale := filepath.Join(base, "stats", "stale.html")
+
+	if err := os.MkdirAll(filepath.Dir(stale), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(stale, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &project{base: base, options: &options{Force: true}}
+
+	if err := p.init(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("expected -f to clear stats, got %v", err)
+	}
+}
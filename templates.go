@@ -0,0 +1,232 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// Holds the default page templates, partials being prefixed with an underscore.
+//
+//go:embed all:templates
+var embedded embed.FS
+
+// Template file names end in this.
+const templateExt = ".html.tmpl"
+
+// Names single file templates, which get passed page data the way they were
+// before view models, as a map of fields by name.
+const legacyTemplate = "legacy"
+
+// Maps page kinds to templates, partials included.
+type templates map[string]*template.Template
+
+// Helps render a page of a given kind.
+func (t templates) render(w io.Writer, kind string, p page) error {
+	tpl, ok := t[kind]
+
+	if !ok {
+		return fmt.Errorf("no template for %s pages", kind)
+	}
+
+	p.Kind = kind
+
+	if tpl.Name() == legacyTemplate {
+		p.Data = flatten(p.Data)
+	}
+
+	return tpl.Execute(w, p)
+}
+
+// Helps catch template errors before writing out any pages by rendering
+// sample pages of every kind. Copies are rendered, templates that have been
+// executed being impossible to clone.
+func (t templates) check() error {
+	samples := fixtures()
+	c := t.clone(nil)
+
+	for _, kind := range kinds {
+		for _, p := range samples[kind] {
+			if err := c.render(io.Discard, kind, p); err != nil {
+				return fmt.Errorf("%s: %v", kind, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Helps turn view models into maps of fields by name, so that templates
+// probing for fields missing from a kind of page get nothing rather than
+// an error. Branch pages list commits at the top level as they used to.
+func flatten(data interface{}) map[string]interface{} {
+	results := make(map[string]interface{})
+
+	v := reflect.ValueOf(data)
+
+	if v.Kind() != reflect.Struct {
+		return results
+	}
+
+	for i := 0; i < v.NumField(); i++ {
+		if f := v.Type().Field(i); f.IsExported() {
+			results[f.Name] = v.Field(i).Interface()
+		}
+	}
+
+	if b, ok := data.(branchView); ok {
+		results["Commits"] = b.Branch.Commits
+	}
+
+	return results
+}
+
+// Helps copy templates for tweaking functions per project.
+func (t templates) clone(funcMap template.FuncMap) templates {
+	results := make(templates)
+
+	for k, tpl := range t {
+		results[k] = template.Must(tpl.Clone()).Funcs(funcMap)
+	}
+
+	return results
+}
+
+// Helps read template sources by file name, files in each of `dirs` taking
+// the place of embedded defaults and earlier files by the same name.
+func templateSources(dirs ...string) (map[string]string, error) {
+	results := make(map[string]string)
+
+	if err := readTemplates(embedded, "templates", results); err != nil {
+		return nil, err
+	}
+
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+
+		if err := readTemplates(os.DirFS(dir), ".", results); err != nil {
+			return nil, err
+		}
+	}
+
+	return results, nil
+}
+
+func readTemplates(fsys fs.FS, dir string, results map[string]string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+
+	if err != nil {
+		return fmt.Errorf("unable to list templates: %v", err)
+	}
+
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), templateExt) {
+			continue
+		}
+
+		bs, err := fs.ReadFile(fsys, filepath.ToSlash(filepath.Join(dir, e.Name())))
+
+		if err != nil {
+			return fmt.Errorf("unable to read template: %v", err)
+		}
+
+		results[e.Name()] = string(bs)
+	}
+
+	return nil
+}
+
+// Helps parse templates for each page kind on top of shared partials. Partials
+// are named after their file, `_header.html.tmpl` making for "header".
+func parseTemplates(sources map[string]string, funcMap template.FuncMap) (templates, error) {
+	base := template.New("").Funcs(funcMap)
+
+	for name, src := range sources {
+		if !strings.HasPrefix(name, "_") {
+			continue
+		}
+
+		partial := strings.TrimSuffix(strings.TrimPrefix(name, "_"), templateExt)
+
+		if _, err := base.New(partial).Parse(src); err != nil {
+			return nil, fmt.Errorf("unable to parse partial: %v", err)
+		}
+	}
+
+	results := make(templates)
+
+	for _, k := range kinds {
+		src, ok := sources[k+templateExt]
+
+		if !ok {
+			return nil, fmt.Errorf("missing template for %s pages", k)
+		}
+
+		t, err := template.Must(base.Clone()).New(k).Parse(src)
+
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse template: %v", err)
+		}
+
+		results[k] = t
+	}
+
+	return results, nil
+}
+
+// Helps parse a single template file used for pages of every kind, the way
+// templates were set up before being split per page kind.
+func parseTemplate(src string, funcMap template.FuncMap) (templates, error) {
+	t, err := template.New(legacyTemplate).Funcs(funcMap).Parse(src)
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse template: %v", err)
+	}
+
+	results := make(templates)
+
+	for _, k := range kinds {
+		results[k] = t
+	}
+
+	return results, nil
+}
+
+// Helps load page templates from `path`, either a directory of overrides
+// or a single template file, falling back to the theme's templates if any
+// and embedded defaults.
+func loadTemplates(theme string, path string, funcMap template.FuncMap) (templates, error) {
+	if path != "" {
+		fi, err := os.Stat(path)
+
+		if err != nil {
+			return nil, fmt.Errorf("unable to read template: %v", err)
+		}
+
+		if !fi.IsDir() {
+			bs, err := os.ReadFile(path)
+
+			if err != nil {
+				return nil, fmt.Errorf("unable to read template: %v", err)
+			}
+
+			return parseTemplate(string(bs), funcMap)
+		}
+	}
+
+	sources, err := templateSources(themeTemplateDir(theme), path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return parseTemplates(sources, funcMap)
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadTemplates(t *testing.T) {
+	dir := t.TempDir()
+
+	// Override a partial and a page kind, leaving the rest to the defaults.
+	files := map[string]string{
+		"_footer.html.tmpl": "<footer>custom</footer>",
+		"branch.html.tmpl":  `{{template "header" .}}<p>{{.Title}}</p>{{template "footer" .}}`,
+		"notes.txt":         "ignored",
+	}
+
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tpl, err := loadTemplates("", dir, funcMap())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, k := range kinds {
+		if _, ok := tpl[k]; !ok {
+			t.Errorf("missing %s template", k)
+		}
+	}
+
+	var sb strings.Builder
+
+	if err := tpl.render(&sb, "branch", page{Title: "Jimbo: main", Data: branchView{}}); err != nil {
+		t.Fatal(err)
+	}
+
+	got := sb.String()
+
+	for _, want := range []string{"<!DOCTYPE html>", "<p>Jimbo: main</p>", "<footer>custom</footer>"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("branch page missing %q", want)
+		}
+	}
+
+	if err := tpl.render(&sb, "nope", page{}); err == nil {
+		t.Error("unknown page kind rendered")
+	}
+}
+
+func TestLoadTemplatesFile(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "page.html.tmpl")
+
+	if err := os.WriteFile(p, []byte(`{{if eq .Kind "branch"}}branch{{else}}other{{end}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tpl, err := loadTemplates("", p, funcMap())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sb strings.Builder
+
+	if err := tpl.render(&sb, "commit", page{Data: commitView{}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if sb.String() != "other" {
+		t.Errorf("got %q, want single template used for all kinds", sb.String())
+	}
+
+	if _, err := loadTemplates("", filepath.Join(t.TempDir(), "missing"), funcMap()); err == nil {
+		t.Error("missing template path accepted")
+	}
+}
+
+func TestDefaultTemplates(t *testing.T) {
+	tpl, err := loadTemplates("", "", funcMap())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	samples := fixtures()
+
+	for _, k := range kinds {
+		if len(samples[k]) == 0 {
+			t.Errorf("no sample %s pages", k)
+		}
+
+		for _, p := range samples[k] {
+			var sb strings.Builder
+
+			if err := tpl.render(&sb, k, p); err != nil {
+				t.Errorf("%s: %v", k, err)
+			}
+		}
+	}
+}
+
+func TestLegacyTemplate(t *testing.T) {
+	// Probes for fields of other kinds of page, the way single file templates
+	// told pages apart before view models.
+	src := `{{with .Data.Source}}source {{.}}{{end}}{{with .Data.Commits}}{{len .}} commits{{end}}{{with .Data.Commit}}commit {{.Abbr}}{{end}}`
+
+	tpl, err := parseTemplate(src, funcMap())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tpl.check(); err != nil {
+		t.Fatalf("legacy template failed checks: %v", err)
+	}
+
+	for _, c := range []struct {
+		kind string
+		data interface{}
+		want string
+	}{
+		{kindIndex, indexView{Source: "/src"}, "source /src"},
+		{kindBranch, branchView{Branch: branch{Commits: make([]commit, 2)}}, "2 commits"},
+		{kindCommit, commitView{Commit: commit{Abbr: "abc"}}, "commit abc"},
+	} {
+		var sb strings.Builder
+
+		if err := tpl.render(&sb, c.kind, page{Data: c.data}); err != nil {
+			t.Fatal(err)
+		}
+
+		if sb.String() != c.want {
+			t.Errorf("%s: got %q, want %q", c.kind, sb.String(), c.want)
+		}
+	}
+
+	broken, err := parseTemplate(`{{.Data.Nope.Deeper}}{{.Title.Nope}}`, funcMap())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := broken.check(); err == nil {
+		t.Error("broken template passed checks")
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// Themes keep page templates and static files such as stylesheets, fonts,
+// and images in subdirectories by these names.
+const (
+	themeAssets    = "assets"
+	themeTemplates = "templates"
+)
+
+// Maps asset paths relative to a theme's `assets/` onto URLs in the output,
+// relative to the page base.
+type assets map[string]string
+
+// Helps templates link to theme assets by their original path, fingerprinted
+// names included.
+func (a assets) url(name string) (string, error) {
+	u, ok := a[path.Clean(name)]
+
+	if !ok {
+		return "", fmt.Errorf("no such asset: %s", name)
+	}
+
+	return u, nil
+}
+
+// Helps link to assets as is when no theme is in use.
+func asset(name string) string {
+	return path.Join(themeAssets, name)
+}
+
+// Helps tell where a theme's templates are, if it has any.
+func themeTemplateDir(theme string) string {
+	if theme == "" {
+		return ""
+	}
+
+	dir := filepath.Join(theme, themeTemplates)
+
+	if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+		return ""
+	}
+
+	return dir
+}
+
+// Helps copy a theme's assets into `dir`, optionally adding a content hash
+// to file names so they can be cached for good. Copies from earlier runs are
+// cleared going by the manifest, files placed by hand staying.
+func copyAssets(theme string, dir string, fingerprint bool) (assets, error) {
+	results := make(assets)
+	src := filepath.Join(theme, themeAssets)
+	dst := filepath.Join(dir, themeAssets)
+
+	if _, err := os.Stat(src); os.IsNotExist(err) {
+		return results, nil
+	}
+
+	if err := sweep(dst); err != nil {
+		return nil, fmt.Errorf("unable to clear assets: %v", err)
+	}
+
+	var written []string
+
+	err := filepath.WalkDir(src, func(p string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, p)
+
+		if err != nil {
+			return err
+		}
+
+		bs, err := os.ReadFile(p)
+
+		if err != nil {
+			return err
+		}
+
+		name := filepath.ToSlash(rel)
+
+		if fingerprint {
+			name = fingerprinted(name, bs)
+		}
+
+		out := filepath.Join(dst, filepath.FromSlash(name))
+
+		if err := os.MkdirAll(filepath.Dir(out), 0755); err != nil {
+			return err
+		}
+
+		if err := os.WriteFile(out, bs, 0644); err != nil {
+			return err
+		}
+
+		results[filepath.ToSlash(rel)] = path.Join(themeAssets, name)
+		written = append(written, name)
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to copy assets: %v", err)
+	}
+
+	if len(written) == 0 {
+		return results, nil
+	}
+
+	return results, writeManifest(dst, written)
+}
+
+// Helps name files after their content, `css/style.css` becoming
+// `css/style.0123abcd.css` say.
+func fingerprinted(name string, bs []byte) string {
+	ext := path.Ext(name)
+	sum := sha256.Sum256(bs)
+
+	return fmt.Sprintf("%s.%x%s", name[:len(name)-len(ext)], sum[:4], ext)
+}
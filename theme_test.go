@@ -0,0 +1,155 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCopyAssets(t *testing.T) {
+	theme := t.TempDir()
+	files := map[string]string{
+		"assets/style.css":      "html { color: red }",
+		"assets/fonts/mono.ttf": "glyphs",
+	}
+
+	for name, src := range files {
+		p := filepath.Join(theme, filepath.FromSlash(name))
+
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(p, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("copy", func(t *testing.T) {
+		dir := t.TempDir()
+		a, err := copyAssets(theme, dir, false)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if u, _ := a.url("fonts/mono.ttf"); u != "assets/fonts/mono.ttf" {
+			t.Errorf("got %q, want assets/fonts/mono.ttf", u)
+		}
+
+		if _, err := os.Stat(filepath.Join(dir, "assets", "style.css")); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("fingerprint", func(t *testing.T) {
+		dir := t.TempDir()
+		stale := filepath.Join(dir, "assets", "style.css")
+		kept := filepath.Join(dir, "assets", "local.css")
+
+		// Copies from an earlier run go, files placed by hand stay.
+		if _, err := copyAssets(theme, dir, false); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(kept, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		a, err := copyAssets(theme, dir, true)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		u, err := a.url("./style.css")
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if u != fingerprinted("assets/style.css", []byte(files["assets/style.css"])) || u == "assets/style.css" {
+			t.Errorf("got %q, want a hashed file name", u)
+		}
+
+		if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(u))); err != nil {
+			t.Error(err)
+		}
+
+		if _, err := os.Stat(stale); !os.IsNotExist(err) {
+			t.Error("stale asset left over")
+		}
+
+		if _, err := os.Stat(kept); err != nil {
+			t.Errorf("hand placed asset removed: %v", err)
+		}
+
+		if _, err := a.url("missing.css"); err == nil {
+			t.Error("missing asset linked to")
+		}
+	})
+}
+
+func TestFingerprinted(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"style.css", "style.2cf24dba.css"},
+		{"js/app.min.js", "js/app.min.2cf24dba.js"},
+		{"LICENSE", "LICENSE.2cf24dba"},
+	}
+
+	for _, tt := range tests {
+		if got := fingerprinted(tt.name, []byte("hello")); got != tt.want {
+			t.Errorf("fingerprinted(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestThemeTemplates(t *testing.T) {
+	theme := t.TempDir()
+	overrides := t.TempDir()
+
+	if err := os.MkdirAll(filepath.Join(theme, themeTemplates), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		filepath.Join(theme, themeTemplates, "_footer.html.tmpl"): `<link href="{{asset "style.css"}}">{{template "nav" .}}`,
+		filepath.Join(theme, themeTemplates, "_nav.html.tmpl"):    "<nav>theme</nav>",
+		filepath.Join(overrides, "_nav.html.tmpl"):                "<nav>override</nav>",
+	}
+
+	for p, src := range files {
+		if err := os.WriteFile(p, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tpl, err := loadTemplates(theme, overrides, funcMap())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sb strings.Builder
+
+	if err := tpl.render(&sb, kindStats, page{Data: statsView{}}); err != nil {
+		t.Fatal(err)
+	}
+
+	got := sb.String()
+
+	for _, want := range []string{`<link href="assets/style.css">`, "<nav>override</nav>"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("stats page missing %q", want)
+		}
+	}
+
+	// Themes without templates of their own fall back to the defaults.
+	if _, err := loadTemplates(t.TempDir(), "", funcMap()); err != nil {
+		t.Error(err)
+	}
+}
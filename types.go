@@ -3,26 +3,36 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"html/template"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
 )
 
 type void struct{}
 
-// Data is the generic content map passed on to the page template.
-type Data map[string]interface{}
 type page struct {
 	Base string
-	Data
+	// Holds the view model matching the page kind.
+	Data interface{}
+	// Tells which kind of page this is, "commit" say.
+	Kind  string
 	Title string
 }
 
 type branch struct {
-	Commits []commit
-	Name    string
-	Project string
+	Commits   []commit
+	Languages breakdown
+	Name      string
+	Project   string
+}
+
+type tag struct {
+	Hash      string
+	Languages breakdown
+	Name      string
 }
 
 func (b branch) String() string {
@@ -30,15 +40,128 @@ func (b branch) String() string {
 }
 
 type diff struct {
-	Body   string
-	Commit commit
+	Body string
+	// Maps file paths to line counts for file diffs left out of the page.
+	Collapsed map[string]int
+	// Marks merge commit diffs against all parents at once.
+	Combined bool
+	Commit   commit
+	// Lists file diffs when split into per file pages.
+	Files  []patch
 	Parent string
+	// Points to a file in `Files` on per file pages, zero otherwise.
+	Part  int
+	Split bool
+}
+
+// Name returns the diff page file name, optionally for the side by side variant.
+func (d diff) Name(split bool) string {
+	return d.Page(d.Part, split)
+}
+
+// Page returns the file name for any of the per file diff pages, zero meaning all files.
+func (d diff) Page(part int, split bool) string {
+	name := d.base()
+
+	if part > 0 {
+		name = fmt.Sprintf("%s-%d", name, part)
+	}
+
+	if split {
+		name = fmt.Sprintf("%s-split", name)
+	}
+
+	return fmt.Sprintf("%s.html", name)
+}
+
+// Patch returns the raw patch file name.
+func (d diff) Patch() string {
+	return fmt.Sprintf("%s.patch", d.base())
+}
+
+func (d diff) base() string {
+	if d.Combined {
+		return "diff-cc"
+	}
+
+	return fmt.Sprintf("diff-%s", d.Parent)
+}
+
+// Helps break up a diff per file.
+type patch struct {
+	Body  string
+	Index int
+	Lines int
+	Path  string
 }
 
 type overview struct {
-	Body   string
-	Hash   string
-	Parent string
+	Body string
+	// Marks merge commits, whose files may show up in the combined diff too.
+	Combined bool
+	Files    []stat
+	Hash     string
+	Parent   string
+	// Lists paths showing up in a merge commit's combined diff.
+	Resolved []string
+}
+
+// Describes a single file's changes against a parent, renames and copies included.
+type stat struct {
+	Adds    int
+	Bin     bool
+	Dels    int
+	NewSize int64
+	OldPath string
+	OldSize int64
+	Path    string
+	// Percentage of content kept across renames and copies.
+	Similarity int
+	// Git's status letter: A, C, D, M, R, T, or U.
+	Status string
+}
+
+// Kind spells out the status letter.
+func (s stat) Kind() string {
+	switch s.Status {
+	case "A":
+		return "added"
+	case "C":
+		return "copied"
+	case "D":
+		return "deleted"
+	case "R":
+		return "renamed"
+	case "T":
+		return "type changed"
+	case "U":
+		return "unmerged"
+	}
+
+	return "modified"
+}
+
+// Adds up per file changes.
+type summary struct {
+	Adds  int
+	Bins  int
+	Dels  int
+	Files int
+}
+
+func summarize(files []stat) summary {
+	s := summary{Files: len(files)}
+
+	for _, f := range files {
+		s.Adds += f.Adds
+		s.Dels += f.Dels
+
+		if f.Bin {
+			s.Bins++
+		}
+	}
+
+	return s
 }
 
 type hash struct {
@@ -67,23 +190,153 @@ type show struct {
 }
 
 type commit struct {
-	Branch  string
-	Body    string
-	Abbr    string
-	History []overview
-	Parents []string
-	Hash    string
-	Author  author
-	Date    time.Time
-	Project string
-	Tree    []object
-	Types   map[string]bool
-	Subject string
+	Branch    string
+	Body      string
+	Abbr      string
+	History   []overview
+	Parents   []string
+	Hash      string
+	Author    author
+	Committed time.Time
+	Committer author
+	Date      time.Time
+	Notes     []note
+	Project   string
+	Signature signature
+	Trailers  []trailer
+	Totals    summary
+	Tree      []object
+	Types     map[string]bool
+	Subject   string
+}
+
+// Trailer returns the values of all trailers matching `key`, case insensitive.
+func (c commit) Trailer(key string) []string {
+	var results []string
+
+	for _, t := range c.Trailers {
+		if strings.EqualFold(t.Key, key) {
+			results = append(results, t.Value)
+		}
+	}
+
+	return results
+}
+
+// CoAuthors returns the identities listed in `Co-authored-by` trailers,
+// addresses going by the privacy mode trailers were redacted with.
+func (c commit) CoAuthors() []author {
+	var results []author
+
+	for _, v := range c.Trailer("Co-authored-by") {
+		name, email, _ := strings.Cut(v, "<")
+
+		results = append(results, author{
+			Email: strings.TrimSuffix(strings.TrimSpace(email), ">"),
+			Name:  strings.TrimSpace(name),
+		})
+	}
+
+	return results
+}
+
+// Redact returns a copy of the commit with email addresses in the message,
+// trailers, and signature treated according to the privacy `mode`.
+func (c commit) Redact(mode string) commit {
+	c.Subject = scrub(c.Subject, mode)
+	c.Body = scrub(c.Body, mode)
+	c.Signature.Signer = scrub(c.Signature.Signer, mode)
+
+	trailers := make([]trailer, len(c.Trailers))
+
+	for i, t := range c.Trailers {
+		trailers[i] = trailer{Key: t.Key, Value: scrub(t.Value, mode)}
+	}
+
+	c.Trailers = trailers
+
+	return c
+}
+
+// Holds a commit's annotation under a given notes ref.
+type note struct {
+	Body string
+	Ref  string
+}
+
+// Git's `Key: value` lines at the end of commit messages, `Signed-off-by` say.
+type trailer struct {
+	Key   string
+	Value string
+}
+
+type signature struct {
+	Key    string
+	Signer string
+	// Git's %G? placeholder, empty if not checked.
+	Status string
+}
+
+// String spells out the signature status.
+func (s signature) String() string {
+	switch s.Status {
+	case "G":
+		return "good"
+	case "B":
+		return "bad"
+	case "U":
+		return "good, unknown validity"
+	case "X":
+		return "good, expired"
+	case "Y":
+		return "good, made by an expired key"
+	case "R":
+		return "good, made by a revoked key"
+	case "E":
+		return "unable to check"
+	case "N":
+		return "none"
+	}
+
+	return ""
 }
 
 type author struct {
 	Email string
-	Name  string
+	// Stands in for the email address when hashing for privacy.
+	Hash string
+	Name string
+	// Holds the page directory name once told apart from other authors.
+	slug string
+}
+
+// Identicon returns an inline SVG drawn from the hashed email, if any.
+func (a author) Identicon() template.HTML {
+	return identicon(a.Hash)
+}
+
+// Slug returns the author's page directory name. Authors are told apart by
+// name much like `git shortlog` does, `.mailmap` taking care of merging
+// identities ahead of time, and names that slugify alike by `disambiguate`.
+func (a author) Slug() string {
+	if a.slug != "" {
+		return a.slug
+	}
+
+	return slugify(a.Name)
+}
+
+// Collects an author's commits across branches.
+type contributor struct {
+	Author author
+	// Lists commits crediting the author in `Co-authored-by` trailers.
+	CoAuthored []commit
+	Commits    []commit
+	Emails     []string
+	First      time.Time
+	Last       time.Time
+	Name       string
+	Slug       string
 }
 
 // https://stackoverflow.com/questions/28322997/how-to-get-a-list-of-values-into-a-flag-in-golang/
@@ -103,18 +356,45 @@ func (f *manyflag) String() string {
 }
 
 type options struct {
-	Branches manyflag `json:"branches"`
-	config   string
-	Export   bool   `json:"export"`
-	Force    bool   `json:"force"`
-	Name     string `json:"name"`
-	Quiet    bool   `json:"quiet"`
-	Source   string `json:"source"`
-	Template string `json:"template"`
+	Branches       manyflag `json:"branches"`
+	CollapseLines  int      `json:"collapse-lines"`
+	config         string
+	Description    string       `json:"description"`
+	Fingerprint    bool         `json:"fingerprint"`
+	Force          bool         `json:"force"`
+	Homepage       string       `json:"homepage"`
+	HotspotWindows string       `json:"hotspot-windows"`
+	Keyring        string       `json:"keyring"`
+	Languages      manyflag     `json:"languages"`
+	LinkRules      manyflag     `json:"link-rules"`
+	Locale         string       `json:"locale"`
+	Mailmap        string       `json:"mailmap"`
+	Name           string       `json:"name"`
+	Notes          manyflag     `json:"notes"`
+	Pages          string       `json:"pages"`
+	Privacy        string       `json:"privacy"`
+	Projects       []repository `json:"projects,omitempty"`
+	Quiet          bool         `json:"quiet"`
+	Schema         string       `json:"$schema,omitempty"`
+	Source         string       `json:"source"`
+	SplitLines     int          `json:"split-lines"`
+	Template       string       `json:"template"`
+	Theme          string       `json:"theme"`
+	Topics         manyflag     `json:"topics"`
+	Version        int          `json:"version"`
+	Zone           string       `json:"zone"`
+}
+
+// Maps commit message references onto URLs, `$1` style expansion included.
+type linkrule struct {
+	Pattern *regexp.Regexp
+	URL     string
 }
 
 // Helps store options as JSON.
 func (o *options) save(p string) error {
+	o.Version = configVersion
+
 	bs, err := json.MarshalIndent(o, "", "  ")
 
 	if err != nil {
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"time"
+)
+
+// Page kinds, each rendered using a template of its own and passed the
+// matching view model below as `.Data`.
+const (
+	kindAuthor   = "author"
+	kindBranch   = "branch"
+	kindCommit   = "commit"
+	kindDiff     = "diff"
+	kindHotspots = "hotspots"
+	kindIndex    = "index"
+	kindObject   = "object"
+	kindPage     = "page"
+	kindSite     = "site"
+	kindStats    = "stats"
+)
+
+// Lists page kinds in the order templates are checked.
+var kinds = []string{kindAuthor, kindBranch, kindCommit, kindDiff, kindHotspots, kindIndex, kindObject, kindPage, kindSite, kindStats}
+
+// Points back to the branch and commit a page belongs to for navigating.
+type trail struct {
+	Branch string
+	Commit string
+}
+
+type siteView struct {
+	Project  string
+	Projects []listing
+}
+
+type indexView struct {
+	About    metadata
+	Authors  []contributor
+	Branches []branch
+	Project  string
+	Source   string
+	Tags     []tag
+}
+
+type statsView struct {
+	Project string
+	Stats   stats
+}
+
+type hotspotsView struct {
+	Hotspots []window
+	Project  string
+}
+
+type authorView struct {
+	Author  contributor
+	Project string
+}
+
+type branchView struct {
+	Branch  branch
+	Project string
+}
+
+type commitView struct {
+	Commit  commit
+	Path    trail
+	Project string
+}
+
+type diffView struct {
+	Diff    diff
+	Path    trail
+	Project string
+}
+
+type objectView struct {
+	Object  show
+	Path    trail
+	Project string
+}
+
+type pageView struct {
+	Page    extra
+	Project string
+}
+
+// Helps check templates against sample pages of every kind, lists and
+// optional fields filled in so that every branch of a template gets a go.
+func fixtures() map[string][]page {
+	date := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	hash := "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+	who := author{Email: "jimbo@example.com", Hash: "fe0e", Name: "Jimbo"}
+	langs := breakdown{{Bytes: 100, Files: 1, Lines: 10, Name: "Go"}}
+	files := []stat{{Adds: 1, Dels: 1, Path: "main.go", Status: "M"}}
+
+	c := commit{
+		Abbr:      hash[:7],
+		Author:    who,
+		Body:      "Fixes #1",
+		Branch:    "main",
+		Committed: date.Add(time.Hour),
+		Committer: author{Name: "Jimbo's friend"},
+		Date:      date,
+		Hash:      hash,
+		History:   []overview{{Body: " main.go | 2 +-\n", Files: files, Hash: hash, Parent: hash}},
+		Notes:     []note{{Body: "Reviewed", Ref: "review"}},
+		Parents:   []string{hash, hash},
+		Signature: signature{Key: "ABCD", Signer: "Jimbo", Status: "G"},
+		Subject:   "Initial commit",
+		Totals:    summarize(files),
+		Trailers:  []trailer{{Key: "Signed-off-by", Value: "Jimbo <jimbo@example.com>"}},
+		Tree:      []object{{Hash: hash, Path: "main.go"}},
+	}
+
+	b := branch{Commits: []commit{c}, Languages: langs, Name: "main", Project: "Jimbo"}
+	p := contributor{Author: who, CoAuthored: []commit{c}, Commits: []commit{c}, Emails: []string{who.Email}, First: date, Last: date, Name: who.Name, Slug: who.Slug()}
+	hot := []hotfile{{Adds: 1, Commits: 1, Dels: 1, Latest: hash, Path: "main.go"}}
+	body := "diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n@@ -1 +1 @@\n-package jimbo\n+package main\n"
+
+	s := stats{Authors: []contributor{p}, Commits: 1, Files: hot, Monthly: []bucket{{Commits: 1, Start: date}}, Weekly: []bucket{{Commits: 1, Start: date}}}
+	s.Punch[1][15] = 1
+
+	d := diff{Body: body, Commit: c, Files: []patch{{Body: body, Index: 1, Lines: 6, Path: "main.go"}}, Parent: hash}
+	o := show{Body: "package main\n", Lines: []int{1}, object: c.Tree[0]}
+	path := trail{Branch: "main", Commit: hash}
+
+	// Diffs come as file indices, unified, side by side, or combined.
+	unified, split, combined := d, d, d
+	unified.Part = 1
+	split.Part, split.Split = 1, true
+	combined.Combined, combined.Files = true, nil
+
+	bin := o
+	bin.Bin, bin.Body, bin.Lines = true, "", nil
+
+	return map[string][]page{
+		kindAuthor: {{Data: authorView{Author: p, Project: "Jimbo"}}},
+		kindBranch: {{Data: branchView{Branch: b, Project: "Jimbo"}}},
+		kindCommit: {{Data: commitView{Commit: c, Path: trail{Branch: "main"}, Project: "Jimbo"}}},
+		kindDiff: {
+			{Data: diffView{Diff: d, Path: path, Project: "Jimbo"}},
+			{Data: diffView{Diff: unified, Path: path, Project: "Jimbo"}},
+			{Data: diffView{Diff: split, Path: path, Project: "Jimbo"}},
+			{Data: diffView{Diff: combined, Path: path, Project: "Jimbo"}},
+		},
+		kindHotspots: {{Data: hotspotsView{Hotspots: []window{{Days: 30, Files: hot}, {Files: hot}}, Project: "Jimbo"}}},
+		kindIndex: {{Data: indexView{
+			About:    metadata{Description: "Sample project", Homepage: "https://example.com", License: "MIT", Topics: []string{"git"}},
+			Authors:  []contributor{p},
+			Branches: []branch{b},
+			Project:  "Jimbo",
+			Source:   "https://example.com/jimbo.git",
+			Tags:     []tag{{Hash: hash, Languages: langs, Name: "v1"}},
+		}}},
+		kindObject: {
+			{Data: objectView{Object: o, Path: path, Project: "Jimbo"}},
+			{Data: objectView{Object: bin, Path: path, Project: "Jimbo"}},
+		},
+		kindPage:  {{Data: pageView{Page: extra{Body: markdown("# About\n\nJimbo's *own* words."), Slug: "about", Title: "About"}, Project: "Jimbo"}}},
+		kindSite:  {{Data: siteView{Project: "Jimbo", Projects: []listing{{metadata: metadata{Description: "Sample project", License: "MIT", Topics: []string{"git"}}, Name: "Jimbo", Path: "jimbo/", Updated: date}}}}},
+		kindStats: {{Data: statsView{Project: "Jimbo", Stats: s}}},
+	}
+}